@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var buildOffline bool
+
+// buildCmd предварительная сборка go миграций в кэш.
+var buildCmd = &cobra.Command{
+	Use:   "build",
+	Short: "Сборка go миграций в кэш без применения",
+	Args:  cobra.MinimumNArgs(0),
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Parent().PersistentPreRunE(cmd.Parent(), args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		const errBuildPrefix = "сборка миграций: "
+
+		m, err := newMigrator(buildOffline)
+		if err != nil {
+			return fmt.Errorf("%s%w", errBuildPrefix, err)
+		}
+
+		list, err := m.Build()
+
+		builder := strings.Builder{}
+		for _, res := range list {
+			builder.WriteString(fmt.Sprintf("%s -> %s\n", res.Name, res.Path))
+		}
+		fmt.Print(builder.String())
+
+		if err != nil {
+			return fmt.Errorf("%s%w", errBuildPrefix, err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(buildCmd)
+	buildCmd.Flags().BoolVar(&buildOffline, "offline", true,
+		"Сборка всех go миграций каталога без подключения к базе")
+}
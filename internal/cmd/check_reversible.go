@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// checkReversibleCmd проверка обратимости миграций.
+var checkReversibleCmd = &cobra.Command{
+	Use:   "check-reversible",
+	Short: "Применение миграций с проверкой, что откат восстанавливает схему базы",
+	Args:  cobra.MinimumNArgs(0),
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Parent().PersistentPreRunE(cmd.Parent(), args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		const errReversiblePrefix = "проверка обратимости миграций: "
+
+		m, err := newMigrator(false)
+		if err != nil {
+			return fmt.Errorf("%s%w", errReversiblePrefix, err)
+		}
+
+		if err = m.SetOutOfOrderPolicy(outOfOrder); err != nil {
+			return fmt.Errorf("%s%w", errReversiblePrefix, err)
+		}
+
+		list, err := m.CheckReversible()
+
+		builder := strings.Builder{}
+		for _, res := range list {
+			switch {
+			case res.Err != nil:
+				builder.WriteString(fmt.Sprintf("FAIL  %s\n", res.Name))
+			case len(res.Changes) > 0:
+				builder.WriteString(fmt.Sprintf("FAIL  %s: откат не восстановил объекты схемы\n", res.Name))
+				for _, c := range res.Changes {
+					builder.WriteString("      " + strings.ReplaceAll(c.String(), "\n", "\n      ") + "\n")
+				}
+			default:
+				builder.WriteString(fmt.Sprintf("OK    %s\n", res.Name))
+			}
+		}
+
+		fmt.Print(builder.String())
+
+		if err != nil {
+			return fmt.Errorf("%s%w", errReversiblePrefix, err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(checkReversibleCmd)
+}
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dimonk33/sql-migrator/pkg/gomigrator"
+	"github.com/spf13/cobra"
+)
+
+// Секция файла конфигурации с параметрами подключения к именованным базам.
+const targetsConfigKey = "targets"
+
+var (
+	compareTargets []string
+
+	errDrift = errors.New("базы различаются")
+)
+
+// compareCmd сравнение двух баз.
+var compareCmd = &cobra.Command{
+	Use:   "compare",
+	Short: "Сравнение примененных миграций и схемы двух баз",
+	Long: `Сравнение примененных миграций и схемы двух баз.
+Параметры подключения задаются в файле конфигурации в секции targets:
+
+targets:
+  staging:
+    db-host: staging.local
+    db-name: app
+  prod:
+    db-host: prod.local
+    db-name: app
+
+или переменными среды GM_TARGETS_<ИМЯ>_DB_HOST и т.д.
+Незаданные параметры берутся из общих флагов подключения.`,
+	Args: cobra.MinimumNArgs(0),
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Parent().PersistentPreRunE(cmd.Parent(), args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		const errComparePrefix = "сравнение баз: "
+
+		if len(compareTargets) != 2 {
+			return fmt.Errorf("%sнужно указать две цели --target", errComparePrefix)
+		}
+
+		migrators := make([]*gomigrator.Migrator, 0, len(compareTargets))
+		for _, name := range compareTargets {
+			param, err := targetConnParam(name)
+			if err != nil {
+				return fmt.Errorf("%s%w", errComparePrefix, err)
+			}
+
+			m, err := gomigrator.New(logg, migrateDir, &param)
+			if err != nil {
+				return fmt.Errorf("%s%s: %w", errComparePrefix, name, err)
+			}
+			migrators = append(migrators, m)
+		}
+
+		report, err := migrators[0].Compare(migrators[1])
+		if err != nil {
+			return fmt.Errorf("%s%w", errComparePrefix, err)
+		}
+
+		fmt.Print(formatCompareReport(report, compareTargets[0], compareTargets[1]))
+
+		if !report.Empty() {
+			return fmt.Errorf("%s%w", errComparePrefix, errDrift)
+		}
+
+		return nil
+	},
+}
+
+// targetConnParam параметры подключения цели из секции targets конфигурации.
+func targetConnParam(name string) (gomigrator.DBConnParam, error) {
+	param := dbParam
+	if config == nil {
+		return param, fmt.Errorf("цель %s не найдена в конфигурации", name)
+	}
+
+	fields := map[string]*string{
+		"db-host":     &param.Host,
+		"db-port":     &param.Port,
+		"db-name":     &param.Name,
+		"db-user":     &param.User,
+		"db-password": &param.Password,
+		"db-ssl":      &param.SSL,
+	}
+
+	found := false
+	for key, field := range fields {
+		configKey := targetsConfigKey + "." + name + "." + key
+		if config.IsSet(configKey) {
+			*field = config.GetString(configKey)
+			found = true
+		}
+	}
+
+	if !found {
+		return param, fmt.Errorf("цель %s не найдена в конфигурации", name)
+	}
+
+	return param, nil
+}
+
+func formatCompareReport(r *gomigrator.CompareReport, first string, second string) string {
+	if r.Empty() {
+		return "Различий нет\n"
+	}
+
+	builder := strings.Builder{}
+
+	writeApplied := func(name string, list []gomigrator.MigrateStatus) {
+		if len(list) == 0 {
+			return
+		}
+		builder.WriteString(fmt.Sprintf("\nМиграции, примененные только в %s:\n", name))
+		for _, mg := range list {
+			builder.WriteString(fmt.Sprintf("  %-40s %s\n", mg.Name, mg.UpdatedAt.Format("2006-01-02 15:04:05")))
+		}
+	}
+
+	writeApplied(first, r.OnlyFirst)
+	writeApplied(second, r.OnlySecond)
+
+	if len(r.Repeatable) > 0 {
+		builder.WriteString("\nПовторяемые миграции с разным содержимым:\n")
+		for _, name := range r.Repeatable {
+			builder.WriteString("  " + name + "\n")
+		}
+	}
+
+	if len(r.Schema) > 0 {
+		builder.WriteString("\nОтличия схемы:\n")
+		for _, c := range r.Schema {
+			switch c.Change {
+			case gomigrator.ChangeRemoved:
+				builder.WriteString(fmt.Sprintf("  %s %s: только в %s\n    %s\n", c.Kind, c.Name, first, indent(c.Before)))
+			case gomigrator.ChangeAdded:
+				builder.WriteString(fmt.Sprintf("  %s %s: только в %s\n    %s\n", c.Kind, c.Name, second, indent(c.After)))
+			default:
+				builder.WriteString(fmt.Sprintf("  %s %s: отличается\n    %s: %s\n    %s: %s\n",
+					c.Kind, c.Name, first, indent(c.Before), second, indent(c.After)))
+			}
+		}
+	}
+
+	return builder.String()
+}
+
+func indent(s string) string {
+	return strings.ReplaceAll(s, "\n", "\n    ")
+}
+
+func init() {
+	rootCmd.AddCommand(compareCmd)
+	compareCmd.Flags().StringArrayVar(&compareTargets, "target", nil, "Имя базы из секции targets конфигурации (указывается дважды)")
+}
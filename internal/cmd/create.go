@@ -8,7 +8,11 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var migrateType string
+var (
+	migrateType    string
+	migrateLayout  string
+	createFromDiff string
+)
 
 // createCmd команда для создания миграций.
 var createCmd = &cobra.Command{
@@ -21,18 +25,34 @@ var createCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		const errCreatePrefix = "создание миграции: "
 
-		m, err := gomigrator.New(logg, migrateDir, &dbParam)
+		// Для миграции по разнице схем нужно подключение к базе
+		m, err := newMigrator(createFromDiff == "")
 		if err != nil {
 			return fmt.Errorf("%s%w", errCreatePrefix, err)
 		}
 
+		if err = m.SetVersioning(versioning); err != nil {
+			return fmt.Errorf("%s%w", errCreatePrefix, err)
+		}
+
+		if err = m.SetLayout(migrateLayout); err != nil {
+			return fmt.Errorf("%s%w", errCreatePrefix, err)
+		}
+
 		mt := strings.ToLower(strings.TrimSpace(migrateType))
 		if err = gomigrator.Validate(mt); err != nil {
 			return fmt.Errorf("%s%w", errCreatePrefix, err)
 		}
 
 		var fname string
-		if fname, err = m.Create(args[0], mt); err != nil {
+		if createFromDiff != "" {
+			if mt != gomigrator.SQLMigration {
+				return fmt.Errorf("%sмиграция по разнице схем может быть только sql", errCreatePrefix)
+			}
+			if fname, err = m.CreateFromDiff(args[0], createFromDiff); err != nil {
+				return fmt.Errorf("%s%w", errCreatePrefix, err)
+			}
+		} else if fname, err = m.Create(args[0], mt); err != nil {
 			return fmt.Errorf("%s%w", errCreatePrefix, err)
 		}
 
@@ -44,5 +64,17 @@ var createCmd = &cobra.Command{
 
 func init() {
 	rootCmd.AddCommand(createCmd)
-	createCmd.Flags().StringVar(&migrateType, "migrate-type", defaultMigrateType, "Тип миграции (sql/go)")
+	createCmd.Flags().StringVar(&migrateType, "migrate-type", defaultMigrateType, "Тип миграции (sql/go/sh)")
+	createCmd.Flags().StringVar(
+		&migrateLayout,
+		"layout",
+		gomigrator.LayoutSingle,
+		"Расположение частей миграции (single - один файл, split - пара .up/.down файлов)",
+	)
+	createCmd.Flags().StringVar(
+		&createFromDiff,
+		"from-diff",
+		"",
+		"Файл желаемой схемы, миграция создается по разнице с текущей схемой базы",
+	)
 }
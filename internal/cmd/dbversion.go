@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/dimonk33/sql-migrator/pkg/gomigrator"
 	"github.com/spf13/cobra"
 )
 
@@ -19,7 +18,7 @@ var versionCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		const errVersionPrefix = "версия: "
 
-		m, err := gomigrator.New(logg, migrateDir, &dbParam)
+		m, err := newMigrator(false)
 		if err != nil {
 			return fmt.Errorf("%s%w", errVersionPrefix, err)
 		}
@@ -3,7 +3,6 @@ package cmd
 import (
 	"fmt"
 
-	"github.com/dimonk33/sql-migrator/pkg/gomigrator"
 	"github.com/spf13/cobra"
 )
 
@@ -18,7 +17,7 @@ var downCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		const errDownPrefix = "откат миграции: "
 
-		m, err := gomigrator.New(logg, migrateDir, &dbParam)
+		m, err := newMigrator(false)
 		if err != nil {
 			return fmt.Errorf("%s%w", errDownPrefix, err)
 		}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	// Файл sql скрипта по умолчанию, "-" - вывод в stdout.
+	defaultExportFile = "export.sql"
+	stdoutFile        = "-"
+)
+
+var (
+	exportFrom    string
+	exportTo      string
+	exportOutput  string
+	exportOffline bool
+)
+
+// exportSQLCmd экспорт миграций в sql скрипт.
+var exportSQLCmd = &cobra.Command{
+	Use:   "export-sql",
+	Short: "Экспорт непримененных sql миграций в один скрипт для выполнения через psql",
+	Args:  cobra.MinimumNArgs(0),
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Parent().PersistentPreRunE(cmd.Parent(), args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		const errExportPrefix = "экспорт миграций: "
+
+		m, err := newMigrator(exportOffline)
+		if err != nil {
+			return fmt.Errorf("%s%w", errExportPrefix, err)
+		}
+
+		var w io.Writer = os.Stdout
+		if exportOutput != stdoutFile {
+			var f *os.File
+			if f, err = os.Create(exportOutput); err != nil {
+				return fmt.Errorf("%s%w", errExportPrefix, err)
+			}
+			defer func() {
+				if errC := f.Close(); errC != nil {
+					logg.Warning("ошибка закрытия файла: " + errC.Error())
+				}
+			}()
+			w = f
+		}
+
+		if err = m.ExportSQL(exportFrom, exportTo, w); err != nil {
+			if exportOutput != stdoutFile {
+				_ = os.Remove(exportOutput)
+			}
+			return fmt.Errorf("%s%w", errExportPrefix, err)
+		}
+
+		if exportOutput != stdoutFile {
+			fmt.Printf("Создан файл: %s", exportOutput)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportSQLCmd)
+	exportSQLCmd.Flags().StringVar(&exportFrom, "from", "", "Начальная версия (включительно)")
+	exportSQLCmd.Flags().StringVar(&exportTo, "to", "", "Конечная версия (включительно)")
+	exportSQLCmd.Flags().BoolVar(&exportOffline, "offline", false, "Экспорт всех миграций диапазона без подключения к базе")
+	exportSQLCmd.Flags().StringVar(&exportOutput, "output", defaultExportFile, "Файл скрипта, - для вывода в консоль")
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dimonk33/sql-migrator/pkg/gomigrator"
+	"github.com/spf13/cobra"
+)
+
+// fixCmd перевод миграций на последовательную нумерацию.
+var fixCmd = &cobra.Command{
+	Use:   "fix",
+	Short: "Перенумерация непримененных миграций с отметкой времени в последовательные номера",
+	Args:  cobra.MinimumNArgs(0),
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Parent().PersistentPreRunE(cmd.Parent(), args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		const errFixPrefix = "перенумерация миграций: "
+
+		m, err := newMigrator(false)
+		if err != nil {
+			return fmt.Errorf("%s%w", errFixPrefix, err)
+		}
+
+		var list []gomigrator.Renamed
+
+		if list, err = m.Fix(); err != nil {
+			return fmt.Errorf("%s%w", errFixPrefix, err)
+		}
+
+		builder := strings.Builder{}
+		for _, item := range list {
+			builder.WriteString(fmt.Sprintf("%s -> %s\n", item.From, item.To))
+		}
+
+		fmt.Print(builder.String())
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(fixCmd)
+}
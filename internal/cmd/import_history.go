@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dimonk33/sql-migrator/pkg/gomigrator"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historySource string
+	historyTable  string
+)
+
+// importHistoryCmd импорт истории миграций другого инструмента.
+var importHistoryCmd = &cobra.Command{
+	Use:   "import-history",
+	Short: "Импорт истории примененных миграций из goose, golang-migrate или Flyway",
+	Args:  cobra.MinimumNArgs(0),
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Parent().PersistentPreRunE(cmd.Parent(), args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		const errImportPrefix = "импорт истории миграций: "
+
+		m, err := newMigrator(false)
+		if err != nil {
+			return fmt.Errorf("%s%w", errImportPrefix, err)
+		}
+
+		var report *gomigrator.ImportReport
+
+		source := strings.ToLower(strings.TrimSpace(historySource))
+		if report, err = m.ImportHistory(source, historyTable); err != nil {
+			return fmt.Errorf("%s%w", errImportPrefix, err)
+		}
+
+		builder := strings.Builder{}
+		builder.WriteString(fmt.Sprintf("Импортировано миграций: %d\n", len(report.Imported)))
+		for _, name := range report.Imported {
+			builder.WriteString("  " + name + "\n")
+		}
+
+		if len(report.Existing) > 0 {
+			builder.WriteString(fmt.Sprintf("Уже записаны: %d\n", len(report.Existing)))
+			for _, name := range report.Existing {
+				builder.WriteString("  " + name + "\n")
+			}
+		}
+
+		if len(report.Unmatched) > 0 {
+			builder.WriteString(fmt.Sprintf("Версии без файла миграции: %d\n", len(report.Unmatched)))
+			for _, v := range report.Unmatched {
+				builder.WriteString("  " + v + "\n")
+			}
+		}
+
+		fmt.Print(builder.String())
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(importHistoryCmd)
+	importHistoryCmd.Flags().StringVar(&historySource, "from", "", "Источник истории (goose/migrate/flyway)")
+	importHistoryCmd.Flags().StringVar(&historyTable, "table", "", "Таблица истории, если отличается от стандартной")
+	_ = importHistoryCmd.MarkFlagRequired("from")
+}
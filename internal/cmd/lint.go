@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/dimonk33/sql-migrator/pkg/gomigrator/lint"
+	"github.com/spf13/cobra"
+)
+
+var (
+	lintOffline  bool
+	lintDisable  []string
+	lintWarn     []string
+	lintError    []string
+	lintFormat   string
+	lintFailOn   string
+	lintRuleList bool
+)
+
+// lintCmd проверка миграций на опасные операции.
+var lintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Проверка непримененных sql миграций на опасные для Postgresql операции",
+	Args:  cobra.MinimumNArgs(0),
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Parent().PersistentPreRunE(cmd.Parent(), args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		const errLintPrefix = "проверка миграций: "
+
+		if lintRuleList {
+			for _, r := range lint.AllRules() {
+				fmt.Printf("%-26s %-8s %s\n", r.ID, r.Severity, r.Description)
+			}
+			return nil
+		}
+
+		if lintFailOn != lint.SeverityError && lintFailOn != lint.SeverityWarning {
+			return fmt.Errorf("%sнеизвестный уровень: %s", errLintPrefix, lintFailOn)
+		}
+
+		cfg := lint.Config{
+			Disabled: lintDisable,
+			Severity: make(map[string]lint.Severity, len(lintWarn)+len(lintError)),
+		}
+		for _, id := range lintWarn {
+			cfg.Severity[id] = lint.SeverityWarning
+		}
+		for _, id := range lintError {
+			cfg.Severity[id] = lint.SeverityError
+		}
+
+		l, err := lint.New(cfg)
+		if err != nil {
+			return fmt.Errorf("%s%w", errLintPrefix, err)
+		}
+
+		m, err := newMigrator(lintOffline)
+		if err != nil {
+			return fmt.Errorf("%s%w", errLintPrefix, err)
+		}
+
+		list, err := l.LintMigrator(m)
+		if err != nil {
+			return fmt.Errorf("%s%w", errLintPrefix, err)
+		}
+
+		if err = lint.Write(os.Stdout, list, lintFormat); err != nil {
+			return fmt.Errorf("%s%w", errLintPrefix, err)
+		}
+
+		if lint.HasErrors(list, lintFailOn) {
+			return fmt.Errorf("%s%w", errLintPrefix, lint.ErrFindings)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+	lintCmd.Flags().BoolVar(&lintOffline, "offline", false, "Проверка всех миграций каталога без подключения к базе")
+	lintCmd.Flags().StringSliceVar(&lintDisable, "disable", nil, "Отключенные правила")
+	lintCmd.Flags().StringSliceVar(&lintWarn, "warn", nil, "Правила с уровнем warning")
+	lintCmd.Flags().StringSliceVar(&lintError, "error", nil, "Правила с уровнем error")
+	lintCmd.Flags().StringVar(&lintFormat, "format", lint.FormatText, "Формат вывода: text, json, github")
+	lintCmd.Flags().StringVar(&lintFailOn, "fail-on", lint.SeverityError, "Уровень проблем, при котором команда завершается с ошибкой: error, warning")
+	lintCmd.Flags().BoolVar(&lintRuleList, "rules", false, "Список правил")
+}
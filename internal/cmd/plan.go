@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dimonk33/sql-migrator/pkg/gomigrator"
+	"github.com/spf13/cobra"
+)
+
+var planOffline bool
+
+// planCmd план применения миграций.
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Список миграций, которые будут применены, в порядке применения",
+	Args:  cobra.MinimumNArgs(0),
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Parent().PersistentPreRunE(cmd.Parent(), args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		const errPlanPrefix = "план миграций: "
+
+		m, err := newMigrator(planOffline)
+		if err != nil {
+			return fmt.Errorf("%s%w", errPlanPrefix, err)
+		}
+
+		var list []gomigrator.PlanItem
+
+		if list, err = m.Plan(); err != nil {
+			return fmt.Errorf("%s%w", errPlanPrefix, err)
+		}
+
+		builder := strings.Builder{}
+		builder.WriteString(`
+Версия          Идентификатор миграции
+-------------------------------------------------------------------------------
+`)
+		for _, item := range list {
+			builder.WriteString(fmt.Sprintf("%-15s %s", item.Version, item.Name))
+			if item.OutOfOrder {
+				builder.WriteString(" (вне очереди)")
+			}
+			builder.WriteString("\n")
+		}
+
+		fmt.Print(builder.String())
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(planCmd)
+	planCmd.Flags().BoolVar(&planOffline, "offline", false, "Все миграции каталога без подключения к базе")
+}
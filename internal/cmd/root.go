@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/dimonk33/sql-migrator/internal/logger"
 	"github.com/dimonk33/sql-migrator/pkg/gomigrator"
@@ -23,6 +24,12 @@ const (
 	// Уровень логирования по умолчанию.
 	defaultLoggerLevel = logger.LevelError
 
+	// Политика применения миграций вне очереди по умолчанию.
+	defaultOutOfOrder = gomigrator.OutOfOrderError
+
+	// Схема версионирования новых миграций по умолчанию.
+	defaultVersioning = gomigrator.VersionTimestamp
+
 	// Путь до файла конфигурации.
 	defaultConfigFilename = "./config/migrator"
 
@@ -37,7 +44,17 @@ var (
 	migrateDir string
 	dbParam    gomigrator.DBConnParam
 	logLevel   string
+	outOfOrder string
+	versioning string
+	goCacheDir string
+	goVars     map[string]string
+	goTimeout  time.Duration
+	goBin      string
+	goRoot     string
 	logg       *logger.Logger
+
+	// Настройки из файла конфигурации и переменных среды.
+	config *viper.Viper
 )
 
 // rootCmd базовая команда.
@@ -51,6 +68,30 @@ var rootCmd = &cobra.Command{
 	},
 }
 
+// Создание мигратора: без подключения к базе для команд с флагом --offline.
+func newMigrator(offline bool) (*gomigrator.Migrator, error) {
+	var (
+		m   *gomigrator.Migrator
+		err error
+	)
+
+	if offline {
+		m, err = gomigrator.NewOffline(logg, migrateDir)
+	} else {
+		m, err = gomigrator.New(logg, migrateDir, &dbParam)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	m.SetGoCacheDir(goCacheDir)
+	m.SetGoEnv(logLevel, goVars)
+	m.SetGoTimeout(goTimeout)
+	m.SetGoToolchain(goBin, goRoot)
+
+	return m, nil
+}
+
 // Execute выполнение дочерних команд.
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
@@ -68,6 +109,38 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&dbParam.SSL, "db-ssl", "disable", "Включение SSL для БД")
 	rootCmd.PersistentFlags().StringVar(&migrateDir, "migrate", defaultMigrateDir, "Путь до каталога с миграциями")
 	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", defaultLoggerLevel, "Уровень логирования")
+	rootCmd.PersistentFlags().StringVar(
+		&outOfOrder,
+		"out-of-order",
+		defaultOutOfOrder,
+		"Политика для миграций с версией ниже последней примененной (error/warn/allow)",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&versioning,
+		"versioning",
+		defaultVersioning,
+		"Схема версионирования новых миграций (timestamp/sequential)",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&goCacheDir,
+		"go-cache",
+		"",
+		"Каталог кэша собранных go миграций (по умолчанию в пользовательском каталоге кэша)",
+	)
+	rootCmd.PersistentFlags().StringToStringVar(
+		&goVars,
+		"var",
+		nil,
+		"Переменные go миграций в виде имя=значение, доступны через gomigrator.Env",
+	)
+	rootCmd.PersistentFlags().DurationVar(
+		&goTimeout,
+		"go-timeout",
+		0,
+		"Время выполнения одной go миграции (например 10m), 0 - без ограничения",
+	)
+	rootCmd.PersistentFlags().StringVar(&goBin, "go-bin", "go", "Путь к go для сборки go миграций")
+	rootCmd.PersistentFlags().StringVar(&goRoot, "go-root", "", "GOROOT для сборки go миграций")
 
 	logg = logger.New(logLevel)
 }
@@ -80,17 +153,19 @@ func initializeConfig(cmd *cobra.Command) error {
 
 	if err := v.ReadInConfig(); err != nil {
 		// It's okay if there isn't a config file
-		var _t0 viper.ConfigFileNotFoundError
-		if !errors.Is(err, _t0) {
+		var notFoundErr viper.ConfigFileNotFoundError
+		if !errors.As(err, &notFoundErr) {
 			return err
 		}
 	}
 
 	v.SetEnvPrefix(envPrefix)
-	v.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+	v.SetEnvKeyReplacer(strings.NewReplacer("-", "_", ".", "_"))
 	v.AutomaticEnv()
 	bindFlags(cmd, v)
 
+	config = v
+
 	return nil
 }
 
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/dimonk33/sql-migrator/pkg/gomigrator"
+	"github.com/spf13/cobra"
+)
+
+// Файл схемы по умолчанию.
+const defaultSchemaFile = "schema.sql"
+
+var schemaOutput string
+
+// schemaCmd команды работы со схемой базы.
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Работа со схемой базы данных",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Parent().PersistentPreRunE(cmd.Parent(), args)
+	},
+}
+
+// schemaDumpCmd выгрузка схемы базы.
+var schemaDumpCmd = &cobra.Command{
+	Use:   "dump",
+	Short: "Выгрузка схемы базы в sql файл по системному каталогу",
+	Args:  cobra.MinimumNArgs(0),
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Parent().PersistentPreRunE(cmd.Parent(), args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		const errDumpPrefix = "выгрузка схемы: "
+
+		m, err := newMigrator(false)
+		if err != nil {
+			return fmt.Errorf("%s%w", errDumpPrefix, err)
+		}
+
+		if err = dumpSchema(m, schemaOutput); err != nil {
+			return fmt.Errorf("%s%w", errDumpPrefix, err)
+		}
+
+		return nil
+	},
+}
+
+// dumpSchema записывает схему базы в файл path, "-" - вывод в консоль.
+func dumpSchema(m *gomigrator.Migrator, path string) error {
+	if path == stdoutFile {
+		return m.DumpSchema(os.Stdout)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err = m.DumpSchema(f); err != nil {
+		_ = f.Close()
+		_ = os.Remove(path)
+		return err
+	}
+
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("ошибка закрытия файла: %w", err)
+	}
+
+	fmt.Printf("Создан файл: %s\n", path)
+
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(schemaCmd)
+	schemaCmd.AddCommand(schemaDumpCmd)
+	schemaDumpCmd.Flags().StringVar(&schemaOutput, "output", defaultSchemaFile, "Файл схемы, - для вывода в консоль")
+}
@@ -19,11 +19,15 @@ var statusCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		const errStatusPrefix = "статус: "
 
-		m, err := gomigrator.New(logg, migrateDir, &dbParam)
+		m, err := newMigrator(false)
 		if err != nil {
 			return fmt.Errorf("%s%w", errStatusPrefix, err)
 		}
 
+		if err = m.SetOutOfOrderPolicy(outOfOrder); err != nil {
+			return fmt.Errorf("%s%w", errStatusPrefix, err)
+		}
+
 		var list []gomigrator.MigrateStatus
 
 		if list, err = m.Status(); err != nil {
@@ -43,6 +47,42 @@ var statusCmd = &cobra.Command{
 			))
 		}
 
+		var rlist []gomigrator.RepeatableStatus
+
+		if rlist, err = m.RepeatableStatus(); err != nil {
+			return fmt.Errorf("%s%w", errStatusPrefix, err)
+		}
+
+		if len(rlist) > 0 {
+			builder.WriteString(`
+Повторяемая миграция                    дата применения
+-------------------------------------------------------------------------------
+`)
+			for _, item := range rlist {
+				builder.WriteString(fmt.Sprintf(
+					"%s - %s\n",
+					item.Name,
+					item.UpdatedAt.Format("02/01/2006 15:04:05"),
+				))
+			}
+		}
+
+		var outOfOrderList []gomigrator.Migration
+
+		if outOfOrderList, err = m.OutOfOrder(); err != nil {
+			return fmt.Errorf("%s%w", errStatusPrefix, err)
+		}
+
+		if len(outOfOrderList) > 0 {
+			builder.WriteString(`
+Непримененные миграции с версией ниже последней примененной
+-------------------------------------------------------------------------------
+`)
+			for _, item := range outOfOrderList {
+				builder.WriteString(item.Name + "\n")
+			}
+		}
+
 		fmt.Print(builder.String())
 
 		return nil
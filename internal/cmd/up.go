@@ -1,12 +1,19 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/dimonk33/sql-migrator/pkg/gomigrator"
 	"github.com/spf13/cobra"
 )
 
+var (
+	upVerify     bool
+	upSchemaDump string
+)
+
 // upCmd команда для применения транзакций.
 var upCmd = &cobra.Command{
 	Use:   "up",
@@ -18,20 +25,78 @@ var upCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		const errUpPrefix = "применение миграций: "
 
-		m, err := gomigrator.New(logg, migrateDir, &dbParam)
+		m, err := newMigrator(false)
 		if err != nil {
 			return fmt.Errorf("%s%w", errUpPrefix, err)
 		}
 
+		if err = m.SetOutOfOrderPolicy(outOfOrder); err != nil {
+			return fmt.Errorf("%s%w", errUpPrefix, err)
+		}
+
+		if upVerify {
+			if err = verify(m); err != nil {
+				return fmt.Errorf("%s%w", errUpPrefix, err)
+			}
+			return nil
+		}
+
 		err = m.Up()
 		if err != nil {
 			return fmt.Errorf("%s%w", errUpPrefix, err)
 		}
 
+		if upSchemaDump != "" {
+			if err = dumpSchema(m, upSchemaDump); err != nil {
+				return fmt.Errorf("%sвыгрузка схемы: %w", errUpPrefix, err)
+			}
+		}
+
 		return nil
 	},
 }
 
+// verify проверка миграций с откатом, результат выводится по каждой миграции.
+func verify(m *gomigrator.Migrator) error {
+	list, err := m.Verify()
+	if err != nil {
+		return err
+	}
+
+	failed := false
+	builder := strings.Builder{}
+	for _, res := range list {
+		switch {
+		case res.Err != nil:
+			failed = true
+			builder.WriteString(fmt.Sprintf("FAIL  %s: %v\n", res.Name, res.Err))
+
+			var stmtErr *gomigrator.StatementError
+			if errors.As(res.Err, &stmtErr) {
+				builder.WriteString("      " + strings.ReplaceAll(stmtErr.Statement, "\n", "\n      ") + "\n")
+			}
+		case res.Skipped != "":
+			builder.WriteString(fmt.Sprintf("SKIP  %s: %s\n", res.Name, res.Skipped))
+		case res.NoDown:
+			builder.WriteString(fmt.Sprintf("OK    %s (нет Down части)\n", res.Name))
+		default:
+			builder.WriteString(fmt.Sprintf("OK    %s\n", res.Name))
+		}
+	}
+
+	fmt.Print(builder.String())
+
+	if failed {
+		return gomigrator.ErrVerifyFailed
+	}
+
+	return nil
+}
+
 func init() {
 	rootCmd.AddCommand(upCmd)
+	upCmd.Flags().BoolVar(&upVerify, "verify", false,
+		"Проверка миграций: Up и Down выполняются в транзакции, которая всегда откатывается")
+	upCmd.Flags().StringVar(&upSchemaDump, "schema-dump", "",
+		"Файл, в который выгружается схема базы после применения миграций")
 }
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// validateCmd проверка каталога миграций.
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Проверка миграций перед применением",
+	Args:  cobra.MinimumNArgs(0),
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Parent().PersistentPreRunE(cmd.Parent(), args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		const errValidatePrefix = "проверка миграций: "
+
+		m, err := newMigrator(false)
+		if err != nil {
+			return fmt.Errorf("%s%w", errValidatePrefix, err)
+		}
+
+		if err = m.SetOutOfOrderPolicy(outOfOrder); err != nil {
+			return fmt.Errorf("%s%w", errValidatePrefix, err)
+		}
+
+		if err = m.Validate(); err != nil {
+			return fmt.Errorf("%s%w", errValidatePrefix, err)
+		}
+
+		fmt.Print("Миграции корректны")
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+}
@@ -0,0 +1,286 @@
+package migdb
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const (
+	KindExtension  = "extension"
+	KindType       = "type"
+	KindSequence   = "sequence"
+	KindTable      = "table"
+	KindColumn     = "column"
+	KindConstraint = "constraint"
+	KindIndex      = "index"
+	KindView       = "view"
+	KindFunction   = "function"
+
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// Порядок видов объектов в снимке совпадает с порядком их создания.
+var kindOrder = map[string]int{
+	KindExtension:  0,
+	KindType:       1,
+	KindSequence:   2,
+	KindTable:      3,
+	KindColumn:     4,
+	KindConstraint: 5,
+	KindIndex:      6,
+	KindView:       7,
+	KindFunction:   8,
+}
+
+// CatalogObject объект схемы базы. Для столбцов и ограничений Table - таблица объекта,
+// для столбцов заполняются также номер, тип, значение по умолчанию и признак NOT NULL.
+type CatalogObject struct {
+	Kind       string
+	Schema     string `db:"schema"`
+	Name       string `db:"name"`
+	Table      string `db:"tbl"`
+	Position   int    `db:"position"`
+	Definition string `db:"definition"`
+	ColType    string `db:"col_type"`
+	ColDefault string `db:"col_default"`
+	NotNull    bool   `db:"col_not_null"`
+}
+
+// Catalog снимок схемы базы, упорядоченный по виду объекта и имени.
+type Catalog []CatalogObject
+
+// CatalogChange отличие объекта схемы между двумя снимками.
+type CatalogChange struct {
+	Kind   string
+	Name   string
+	Change string
+	Before string
+	After  string
+}
+
+// FullName имя объекта со схемой, для столбцов и ограничений - с таблицей.
+func (o CatalogObject) FullName() string {
+	name := o.Name
+	if o.Table != "" && (o.Kind == KindColumn || o.Kind == KindConstraint) {
+		name = o.Table + "." + name
+	}
+	if o.Schema != "" {
+		name = o.Schema + "." + name
+	}
+
+	return name
+}
+
+func (o CatalogObject) key() string {
+	return o.Kind + " " + o.FullName()
+}
+
+func (c CatalogChange) String() string {
+	switch c.Change {
+	case ChangeAdded:
+		return fmt.Sprintf("%s %s: добавлен\n  + %s", c.Kind, c.Name, c.After)
+	case ChangeRemoved:
+		return fmt.Sprintf("%s %s: удален\n  - %s", c.Kind, c.Name, c.Before)
+	}
+
+	return fmt.Sprintf("%s %s: изменен\n  - %s\n  + %s", c.Kind, c.Name, c.Before, c.After)
+}
+
+func sortCatalog(c Catalog) {
+	sort.SliceStable(c, func(i, j int) bool {
+		if c[i].Kind != c[j].Kind {
+			return kindOrder[c[i].Kind] < kindOrder[c[j].Kind]
+		}
+		if c[i].Schema != c[j].Schema {
+			return c[i].Schema < c[j].Schema
+		}
+		if c[i].Table != c[j].Table {
+			return c[i].Table < c[j].Table
+		}
+		if c[i].Position != c[j].Position {
+			return c[i].Position < c[j].Position
+		}
+		return c[i].Name < c[j].Name
+	})
+}
+
+// DiffCatalog возвращает объекты, которые отличаются в снимке after от снимка before.
+// Порядок столбцов в таблице не учитывается.
+func DiffCatalog(before Catalog, after Catalog) []CatalogChange {
+	beforeMap := make(map[string]CatalogObject, len(before))
+	for _, o := range before {
+		beforeMap[o.key()] = o
+	}
+
+	afterMap := make(map[string]CatalogObject, len(after))
+	for _, o := range after {
+		afterMap[o.key()] = o
+	}
+
+	out := make([]CatalogChange, 0)
+	for _, o := range before {
+		a, ok := afterMap[o.key()]
+		switch {
+		case !ok:
+			out = append(out, CatalogChange{Kind: o.Kind, Name: o.FullName(), Change: ChangeRemoved, Before: o.Definition})
+		case a.Definition != o.Definition:
+			out = append(out, CatalogChange{
+				Kind:   o.Kind,
+				Name:   o.FullName(),
+				Change: ChangeChanged,
+				Before: o.Definition,
+				After:  a.Definition,
+			})
+		}
+	}
+
+	for _, o := range after {
+		if _, ok := beforeMap[o.key()]; !ok {
+			out = append(out, CatalogChange{Kind: o.Kind, Name: o.FullName(), Change: ChangeAdded, After: o.Definition})
+		}
+	}
+
+	return out
+}
+
+// Условие отбора пользовательских схем, schema ограничивает отбор одной схемой.
+func userSchema(alias string, schema string) string {
+	cond := alias + ".nspname NOT IN ('pg_catalog', 'information_schema') AND " + alias + ".nspname NOT LIKE 'pg\\_%'"
+	if schema != "" {
+		cond += " AND " + alias + ".nspname = " + pq.QuoteLiteral(schema)
+	}
+
+	return cond
+}
+
+// Условие исключения объектов, созданных расширениями.
+func notExtension(catalog string, oid string) string {
+	return "NOT EXISTS (SELECT 1 FROM pg_depend e WHERE e.classid = '" + catalog + "'::regclass AND e.objid = " +
+		oid + " AND e.deptype = 'e')"
+}
+
+// Запросы получения объектов схемы, каждый возвращает schema, name, tbl, position, definition.
+func catalogQueries(schema string) map[string]string {
+	return map[string]string{
+		KindExtension: `SELECT '' AS schema, x.extname AS name, '' AS tbl, 0 AS position,
+				format('CREATE EXTENSION IF NOT EXISTS %I WITH SCHEMA %I', x.extname, n.nspname) AS definition
+			FROM pg_extension x
+			JOIN pg_namespace n ON n.oid = x.extnamespace`,
+		KindType: `SELECT n.nspname AS schema, t.typname AS name, '' AS tbl, 0 AS position,
+				format('CREATE TYPE %I.%I AS ENUM (%s)', n.nspname, t.typname,
+					string_agg(quote_literal(en.enumlabel), ', ' ORDER BY en.enumsortorder)) AS definition
+			FROM pg_type t
+			JOIN pg_namespace n ON n.oid = t.typnamespace
+			JOIN pg_enum en ON en.enumtypid = t.oid
+			WHERE ` + userSchema("n", schema) + ` AND ` + notExtension("pg_type", "t.oid") + `
+			GROUP BY n.nspname, t.typname`,
+		KindSequence: `SELECT n.nspname AS schema, c.relname AS name, COALESCE(owner.relname, '') AS tbl, 0 AS position,
+				format('CREATE SEQUENCE %I.%I AS %s INCREMENT BY %s MINVALUE %s MAXVALUE %s START WITH %s%s',
+					n.nspname, c.relname, format_type(s.seqtypid, NULL), s.seqincrement, s.seqmin, s.seqmax,
+					s.seqstart, CASE WHEN s.seqcycle THEN ' CYCLE' ELSE '' END) AS definition
+			FROM pg_sequence s
+			JOIN pg_class c ON c.oid = s.seqrelid
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			LEFT JOIN pg_depend d ON d.classid = 'pg_class'::regclass AND d.objid = c.oid
+				AND d.refclassid = 'pg_class'::regclass AND d.deptype = 'a'
+			LEFT JOIN pg_class owner ON owner.oid = d.refobjid
+			WHERE ` + userSchema("n", schema) + ` AND ` + notExtension("pg_class", "c.oid") + `
+				AND NOT EXISTS (SELECT 1 FROM pg_depend i WHERE i.classid = 'pg_class'::regclass
+					AND i.objid = c.oid AND i.deptype = 'i')`,
+		KindTable: `SELECT n.nspname AS schema, c.relname AS name, c.relname AS tbl, 0 AS position,
+				format('CREATE %sTABLE %I.%I',
+					CASE WHEN c.relpersistence = 'u' THEN 'UNLOGGED ' ELSE '' END, n.nspname, c.relname) AS definition
+			FROM pg_class c
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE c.relkind IN ('r', 'p') AND ` + userSchema("n", schema) + ` AND ` + notExtension("pg_class", "c.oid"),
+		KindColumn: `SELECT n.nspname AS schema, a.attname AS name, c.relname AS tbl, a.attnum AS position,
+				format('%I %s', a.attname, format_type(a.atttypid, a.atttypmod)) ||
+				CASE a.attidentity
+					WHEN 'a' THEN ' GENERATED ALWAYS AS IDENTITY'
+					WHEN 'd' THEN ' GENERATED BY DEFAULT AS IDENTITY'
+					ELSE '' END ||
+				CASE
+					WHEN a.attgenerated = 's' THEN ' GENERATED ALWAYS AS (' || pg_get_expr(ad.adbin, ad.adrelid) || ') STORED'
+					WHEN ad.adbin IS NOT NULL THEN ' DEFAULT ' || pg_get_expr(ad.adbin, ad.adrelid)
+					ELSE '' END ||
+				CASE WHEN a.attnotnull THEN ' NOT NULL' ELSE '' END AS definition,
+				format_type(a.atttypid, a.atttypmod) AS col_type,
+				CASE WHEN a.attgenerated = '' THEN COALESCE(pg_get_expr(ad.adbin, ad.adrelid), '') ELSE '' END AS col_default,
+				a.attnotnull AS col_not_null
+			FROM pg_attribute a
+			JOIN pg_class c ON c.oid = a.attrelid
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			LEFT JOIN pg_attrdef ad ON ad.adrelid = a.attrelid AND ad.adnum = a.attnum
+			WHERE c.relkind IN ('r', 'p') AND a.attnum > 0 AND NOT a.attisdropped
+				AND ` + userSchema("n", schema) + ` AND ` + notExtension("pg_class", "c.oid"),
+		KindConstraint: `SELECT n.nspname AS schema, con.conname AS name, c.relname AS tbl, 0 AS position,
+				format('ALTER TABLE %I.%I ADD CONSTRAINT %I %s', n.nspname, c.relname, con.conname,
+					pg_get_constraintdef(con.oid, true)) AS definition
+			FROM pg_constraint con
+			JOIN pg_class c ON c.oid = con.conrelid
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE con.contype IN ('p', 'u', 'f', 'c', 'x') AND ` + userSchema("n", schema) + `
+				AND ` + notExtension("pg_class", "c.oid"),
+		KindIndex: `SELECT n.nspname AS schema, ic.relname AS name, c.relname AS tbl, 0 AS position,
+				pg_get_indexdef(i.indexrelid) AS definition
+			FROM pg_index i
+			JOIN pg_class ic ON ic.oid = i.indexrelid
+			JOIN pg_class c ON c.oid = i.indrelid
+			JOIN pg_namespace n ON n.oid = ic.relnamespace
+			WHERE ` + userSchema("n", schema) + ` AND ` + notExtension("pg_class", "c.oid") + `
+				AND NOT EXISTS (SELECT 1 FROM pg_constraint con WHERE con.conindid = i.indexrelid
+					AND con.contype IN ('p', 'u', 'x'))`,
+		KindView: `SELECT n.nspname AS schema, c.relname AS name, '' AS tbl, 0 AS position,
+				format('CREATE %sVIEW %I.%I AS%s',
+					CASE WHEN c.relkind = 'm' THEN 'MATERIALIZED ' ELSE '' END, n.nspname, c.relname,
+					rtrim(pg_get_viewdef(c.oid, true), ';')) AS definition
+			FROM pg_class c
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE c.relkind IN ('v', 'm') AND ` + userSchema("n", schema) + ` AND ` + notExtension("pg_class", "c.oid"),
+		KindFunction: `SELECT n.nspname AS schema, p.proname || '(' || pg_get_function_identity_arguments(p.oid) || ')' AS name,
+				'' AS tbl, 0 AS position, rtrim(pg_get_functiondef(p.oid), E'\n') AS definition
+			FROM pg_proc p
+			JOIN pg_namespace n ON n.oid = p.pronamespace
+			WHERE p.prokind IN ('f', 'p') AND ` + userSchema("n", schema) + ` AND ` + notExtension("pg_proc", "p.oid"),
+	}
+}
+
+// Служебные объекты мигратора не входят в снимок.
+func isServiceObject(o CatalogObject) bool {
+	return o.Table == serviceTableName || o.Kind == KindTable && o.Name == serviceTableName ||
+		o.Kind == KindType && o.Name == enumTableName
+}
+
+// Snapshot снимок пользовательских объектов схемы базы по системному каталогу.
+func (b *Pg) Snapshot(ctx context.Context) (Catalog, error) {
+	return snapshot(ctx, b.conn, "")
+}
+
+func snapshot(ctx context.Context, q sqlx.QueryerContext, schema string) (Catalog, error) {
+	out := make(Catalog, 0)
+	for kind, query := range catalogQueries(schema) {
+		data := make([]CatalogObject, 0)
+		if err := sqlx.SelectContext(ctx, q, &data, query); err != nil {
+			return nil, fmt.Errorf("чтение каталога (%s): %w", kind, err)
+		}
+
+		for _, o := range data {
+			o.Kind = kind
+			o.Definition = strings.TrimSpace(o.Definition)
+			if !isServiceObject(o) {
+				out = append(out, o)
+			}
+		}
+	}
+
+	sortCatalog(out)
+
+	return out, nil
+}
@@ -0,0 +1,53 @@
+package migdb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffCatalog(t *testing.T) {
+	before := Catalog{
+		{Kind: KindTable, Schema: "public", Name: "users", Table: "users", Definition: "CREATE TABLE public.users"},
+		{Kind: KindColumn, Schema: "public", Name: "id", Table: "users", Position: 1, Definition: "id integer NOT NULL"},
+		{Kind: KindColumn, Schema: "public", Name: "name", Table: "users", Position: 2, Definition: "name text"},
+		{Kind: KindIndex, Schema: "public", Name: "users_name_idx", Table: "users", Definition: "CREATE INDEX ..."},
+	}
+	after := Catalog{
+		{Kind: KindTable, Schema: "public", Name: "users", Table: "users", Definition: "CREATE TABLE public.users"},
+		{Kind: KindColumn, Schema: "public", Name: "name", Table: "users", Position: 1, Definition: "name varchar(10)"},
+		{Kind: KindColumn, Schema: "public", Name: "id", Table: "users", Position: 2, Definition: "id integer NOT NULL"},
+		{Kind: KindView, Schema: "public", Name: "active", Definition: "CREATE VIEW public.active AS SELECT 1"},
+	}
+
+	require.Empty(t, DiffCatalog(before, before))
+	require.Equal(t, []CatalogChange{
+		{Kind: KindColumn, Name: "public.users.name", Change: ChangeChanged, Before: "name text", After: "name varchar(10)"},
+		{Kind: KindIndex, Name: "public.users_name_idx", Change: ChangeRemoved, Before: "CREATE INDEX ..."},
+		{Kind: KindView, Name: "public.active", Change: ChangeAdded, After: "CREATE VIEW public.active AS SELECT 1"},
+	}, DiffCatalog(before, after))
+}
+
+func TestSortCatalog(t *testing.T) {
+	c := Catalog{
+		{Kind: KindIndex, Schema: "public", Name: "b_idx"},
+		{Kind: KindColumn, Schema: "public", Name: "z", Table: "t", Position: 1},
+		{Kind: KindColumn, Schema: "public", Name: "a", Table: "t", Position: 2},
+		{Kind: KindExtension, Name: "pgcrypto"},
+		{Kind: KindIndex, Schema: "public", Name: "a_idx"},
+	}
+	sortCatalog(c)
+
+	names := make([]string, 0, len(c))
+	for _, o := range c {
+		names = append(names, o.Name)
+	}
+	require.Equal(t, []string{"pgcrypto", "z", "a", "a_idx", "b_idx"}, names)
+}
+
+func TestIsServiceObject(t *testing.T) {
+	require.True(t, isServiceObject(CatalogObject{Kind: KindTable, Name: serviceTableName, Table: serviceTableName}))
+	require.True(t, isServiceObject(CatalogObject{Kind: KindIndex, Name: "name_uniq_idx", Table: serviceTableName}))
+	require.True(t, isServiceObject(CatalogObject{Kind: KindType, Name: enumTableName}))
+	require.False(t, isServiceObject(CatalogObject{Kind: KindTable, Name: "users", Table: "users"}))
+}
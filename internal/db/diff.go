@@ -0,0 +1,343 @@
+package migdb
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Префикс временной схемы, в которой применяется желаемая схема.
+const scratchSchemaPrefix = "gm_diff_"
+
+var (
+	simpleIdentRe = regexp.MustCompile(`^[a-z_][a-z0-9_$]*$`)
+
+	// Зарезервированные слова, которые нельзя использовать как имя без кавычек.
+	reservedWords = map[string]struct{}{
+		"all": {}, "and": {}, "as": {}, "asc": {}, "case": {}, "check": {}, "column": {}, "constraint": {},
+		"default": {}, "desc": {}, "else": {}, "end": {}, "from": {}, "group": {}, "limit": {}, "not": {},
+		"null": {}, "offset": {}, "or": {}, "order": {}, "primary": {}, "references": {}, "select": {},
+		"table": {}, "then": {}, "to": {}, "union": {}, "user": {}, "when": {}, "where": {}, "window": {},
+		"with": {},
+	}
+)
+
+// DesiredSnapshot применяет запросы желаемой схемы во временной схеме внутри транзакции,
+// которая затем откатывается, и возвращает снимки текущей и желаемой схемы.
+// Объекты желаемой схемы переименовываются в текущую схему, чтобы снимки можно было сравнить.
+// Снимки строятся с пустым search_path, поэтому все имена в определениях содержат схему.
+func (b *Pg) DesiredSnapshot(ctx context.Context, sqlPool []string) (Catalog, Catalog, error) {
+	const logPrefixDiff = "сравнение схемы:"
+
+	tx, err := b.conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer b.txRollback(tx.Tx, logPrefixDiff)
+
+	var current string
+	if err = tx.GetContext(ctx, &current, "SELECT current_schema()"); err != nil {
+		return nil, nil, fmt.Errorf("определение текущей схемы: %w", err)
+	}
+
+	if _, err = tx.ExecContext(ctx, "SET LOCAL search_path TO pg_catalog"); err != nil {
+		return nil, nil, err
+	}
+
+	currentCatalog, err := snapshot(ctx, tx, current)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	scratch := scratchSchemaPrefix + fmt.Sprint(time.Now().UnixNano())
+	prepare := []string{
+		"CREATE SCHEMA " + scratch,
+		"SET LOCAL search_path TO " + scratch + ", " + pq.QuoteIdentifier(current),
+	}
+	for _, s := range prepare {
+		if _, err = tx.ExecContext(ctx, s); err != nil {
+			return nil, nil, fmt.Errorf("создание временной схемы: %w", err)
+		}
+	}
+
+	for i, s := range sqlPool {
+		if _, err = tx.ExecContext(ctx, s); err != nil {
+			return nil, nil, fmt.Errorf("выполнение запроса %d желаемой схемы: %w", i, err)
+		}
+	}
+
+	if _, err = tx.ExecContext(ctx, "SET LOCAL search_path TO pg_catalog"); err != nil {
+		return nil, nil, err
+	}
+
+	desired, err := snapshot(ctx, tx, scratch)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i := range desired {
+		desired[i] = renameSchema(desired[i], scratch, current)
+	}
+
+	return currentCatalog, desired, nil
+}
+
+func renameSchema(o CatalogObject, from string, to string) CatalogObject {
+	if o.Schema == from {
+		o.Schema = to
+	}
+
+	replace := func(s string) string {
+		return strings.ReplaceAll(s, from+".", qualify(to, ""))
+	}
+	o.Definition = strings.ReplaceAll(replace(o.Definition), "WITH SCHEMA "+from, "WITH SCHEMA "+quoteIdent(to))
+	o.ColType = replace(o.ColType)
+	o.ColDefault = replace(o.ColDefault)
+
+	return o
+}
+
+func quoteIdent(name string) string {
+	if _, ok := reservedWords[name]; ok || !simpleIdentRe.MatchString(name) {
+		return pq.QuoteIdentifier(name)
+	}
+
+	return name
+}
+
+// qualify имя объекта со схемой, пустое имя дает префикс схемы с точкой.
+func qualify(schema string, name string) string {
+	if name == "" {
+		return quoteIdent(schema) + "."
+	}
+
+	return quoteIdent(schema) + "." + quoteIdent(name)
+}
+
+// DiffScript возвращает запросы, которые переводят схему from в схему to.
+// Сначала удаляются лишние объекты в порядке, обратном зависимостям, затем создаются
+// и изменяются недостающие. Изменения, которые нельзя выразить автоматически,
+// выводятся комментарием для ручной правки.
+func DiffScript(from Catalog, to Catalog) []string {
+	fromMap := catalogIndex(from)
+	toMap := catalogIndex(to)
+
+	tablesFrom := make(map[string]struct{})
+	tablesTo := make(map[string]struct{})
+	for _, o := range from {
+		if o.Kind == KindTable {
+			tablesFrom[o.Schema+"."+o.Name] = struct{}{}
+		}
+	}
+	for _, o := range to {
+		if o.Kind == KindTable {
+			tablesTo[o.Schema+"."+o.Name] = struct{}{}
+		}
+	}
+
+	// Объекты таблиц, которые создаются или удаляются целиком, отдельно не обрабатываются
+	withTable := func(o CatalogObject, tables map[string]struct{}, other map[string]struct{}) bool {
+		if o.Kind != KindColumn && o.Kind != KindConstraint && o.Kind != KindIndex {
+			return false
+		}
+		key := o.Schema + "." + o.Table
+		_, in := tables[key]
+		_, inOther := other[key]
+		return in && !inOther
+	}
+
+	drops := make([]string, 0)
+	for _, kind := range []string{KindView, KindFunction, KindIndex, KindConstraint, KindColumn, KindTable, KindSequence, KindType, KindExtension} {
+		list := objectsOfKind(from, kind)
+		if kind == KindConstraint {
+			// Внешние ключи удаляются раньше ключей, на которые они ссылаются
+			sort.SliceStable(list, func(i, j int) bool {
+				return isForeignKey(list[i]) && !isForeignKey(list[j])
+			})
+		}
+
+		for _, o := range list {
+			t, ok := toMap[o.key()]
+			switch {
+			case !ok:
+				if withTable(o, tablesFrom, tablesTo) {
+					continue
+				}
+				drops = append(drops, dropStatement(o))
+			case t.Definition != o.Definition && recreated(o.Kind):
+				drops = append(drops, dropStatement(o))
+			}
+		}
+	}
+
+	creates := make([]string, 0)
+	for _, kind := range []string{KindExtension, KindType, KindSequence, KindTable, KindColumn, KindConstraint, KindIndex, KindFunction, KindView} {
+		for _, o := range objectsOfKind(to, kind) {
+			f, ok := fromMap[o.key()]
+			switch {
+			case !ok:
+				if o.Kind == KindColumn && withTable(o, tablesTo, tablesFrom) {
+					continue
+				}
+				creates = append(creates, createStatement(o, to))
+			case f.Definition == o.Definition:
+			case recreated(o.Kind):
+				creates = append(creates, createStatement(o, to))
+			default:
+				creates = append(creates, alterStatements(f, o)...)
+			}
+		}
+	}
+
+	return append(drops, creates...)
+}
+
+func catalogIndex(c Catalog) map[string]CatalogObject {
+	out := make(map[string]CatalogObject, len(c))
+	for _, o := range c {
+		out[o.key()] = o
+	}
+
+	return out
+}
+
+// Объекты вида kind, внешние ключи идут после остальных ограничений.
+func objectsOfKind(c Catalog, kind string) []CatalogObject {
+	out := make([]CatalogObject, 0)
+	for _, o := range c {
+		if o.Kind == kind {
+			out = append(out, o)
+		}
+	}
+
+	sortCatalog(out)
+	if kind == KindConstraint {
+		sort.SliceStable(out, func(i, j int) bool {
+			return !isForeignKey(out[i]) && isForeignKey(out[j])
+		})
+	}
+
+	return out
+}
+
+// Измененные объекты этих видов пересоздаются, остальные изменяются на месте.
+func recreated(kind string) bool {
+	return kind == KindType || kind == KindConstraint || kind == KindIndex || kind == KindView
+}
+
+func dropStatement(o CatalogObject) string {
+	switch o.Kind {
+	case KindView:
+		if strings.HasPrefix(o.Definition, "CREATE MATERIALIZED VIEW") {
+			return "DROP MATERIALIZED VIEW " + qualify(o.Schema, o.Name)
+		}
+		return "DROP VIEW " + qualify(o.Schema, o.Name)
+	case KindFunction:
+		return "DROP ROUTINE " + qualify(o.Schema, "") + o.Name
+	case KindIndex:
+		return "DROP INDEX " + qualify(o.Schema, o.Name)
+	case KindConstraint:
+		return "ALTER TABLE " + qualify(o.Schema, o.Table) + " DROP CONSTRAINT " + quoteIdent(o.Name)
+	case KindColumn:
+		return "ALTER TABLE " + qualify(o.Schema, o.Table) + " DROP COLUMN " + quoteIdent(o.Name)
+	case KindTable:
+		return "DROP TABLE " + qualify(o.Schema, o.Name)
+	case KindSequence:
+		// Последовательность serial столбца удаляется вместе с таблицей
+		return "DROP SEQUENCE IF EXISTS " + qualify(o.Schema, o.Name)
+	case KindType:
+		return "DROP TYPE " + qualify(o.Schema, o.Name)
+	case KindExtension:
+		return "DROP EXTENSION " + quoteIdent(o.Name)
+	}
+
+	return "-- " + o.Kind + " " + o.FullName() + ": удаление не поддерживается"
+}
+
+func createStatement(o CatalogObject, c Catalog) string {
+	switch o.Kind {
+	case KindTable:
+		columns := make([]string, 0)
+		for _, col := range objectsOfKind(c, KindColumn) {
+			if col.Schema == o.Schema && col.Table == o.Name {
+				columns = append(columns, col.Definition)
+			}
+		}
+		return o.Definition + " (" + tableColumns(columns) + ")"
+	case KindColumn:
+		return "ALTER TABLE " + qualify(o.Schema, o.Table) + " ADD COLUMN " + o.Definition
+	}
+
+	return o.Definition
+}
+
+func alterStatements(from CatalogObject, to CatalogObject) []string {
+	switch to.Kind {
+	case KindColumn:
+		return alterColumn(from, to)
+	case KindSequence:
+		s := strings.Replace(to.Definition, "CREATE SEQUENCE", "ALTER SEQUENCE", 1)
+		if !strings.HasSuffix(s, " CYCLE") {
+			s += " NO CYCLE"
+		}
+		return []string{s}
+	case KindFunction:
+		return []string{to.Definition}
+	case KindTable:
+		if strings.HasPrefix(to.Definition, "CREATE UNLOGGED TABLE") {
+			return []string{"ALTER TABLE " + qualify(to.Schema, to.Name) + " SET UNLOGGED"}
+		}
+		return []string{"ALTER TABLE " + qualify(to.Schema, to.Name) + " SET LOGGED"}
+	case KindExtension:
+		if i := strings.LastIndex(to.Definition, " WITH SCHEMA "); i != -1 {
+			return []string{"ALTER EXTENSION " + quoteIdent(to.Name) + " SET SCHEMA " + to.Definition[i+len(" WITH SCHEMA "):]}
+		}
+	}
+
+	return []string{manualChange(from, to)}
+}
+
+func alterColumn(from CatalogObject, to CatalogObject) []string {
+	prefix := "ALTER TABLE " + qualify(to.Schema, to.Table) + " ALTER COLUMN " + quoteIdent(to.Name)
+	out := make([]string, 0)
+
+	if from.ColType != to.ColType {
+		out = append(out, prefix+" TYPE "+to.ColType+" USING "+quoteIdent(to.Name)+"::"+to.ColType)
+	}
+
+	if from.ColDefault != to.ColDefault {
+		if to.ColDefault == "" {
+			out = append(out, prefix+" DROP DEFAULT")
+		} else {
+			out = append(out, prefix+" SET DEFAULT "+to.ColDefault)
+		}
+	}
+
+	if from.NotNull != to.NotNull {
+		if to.NotNull {
+			out = append(out, prefix+" SET NOT NULL")
+		} else {
+			out = append(out, prefix+" DROP NOT NULL")
+		}
+	}
+
+	// Изменения identity и вычисляемых столбцов не выражаются через тип, default и NOT NULL
+	if len(out) == 0 {
+		out = append(out, manualChange(from, to))
+	}
+
+	return out
+}
+
+func manualChange(from CatalogObject, to CatalogObject) string {
+	return fmt.Sprintf("-- %s %s: требуется ручная правка\n-- было: %s\n-- стало: %s",
+		to.Kind, to.FullName(),
+		strings.ReplaceAll(from.Definition, "\n", "\n-- "),
+		strings.ReplaceAll(to.Definition, "\n", "\n-- "))
+}
@@ -0,0 +1,116 @@
+package migdb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testColumn(table string, pos int, name string, colType string, notNull bool, def string) CatalogObject {
+	d := name + " " + colType
+	if def != "" {
+		d += " DEFAULT " + def
+	}
+	if notNull {
+		d += " NOT NULL"
+	}
+
+	return CatalogObject{
+		Kind:       KindColumn,
+		Schema:     "public",
+		Name:       name,
+		Table:      table,
+		Position:   pos,
+		Definition: d,
+		ColType:    colType,
+		ColDefault: def,
+		NotNull:    notNull,
+	}
+}
+
+func TestDiffScript(t *testing.T) {
+	current := Catalog{
+		{Kind: KindTable, Schema: "public", Name: "users", Table: "users", Definition: "CREATE TABLE public.users"},
+		testColumn("users", 1, "id", "integer", true, ""),
+		testColumn("users", 2, "name", "text", false, ""),
+		testColumn("users", 3, "legacy", "text", false, ""),
+		{Kind: KindConstraint, Schema: "public", Name: "users_pkey", Table: "users",
+			Definition: "ALTER TABLE public.users ADD CONSTRAINT users_pkey PRIMARY KEY (id)"},
+		{Kind: KindTable, Schema: "public", Name: "old", Table: "old", Definition: "CREATE TABLE public.old"},
+		testColumn("old", 1, "id", "integer", false, ""),
+	}
+	desired := Catalog{
+		{Kind: KindTable, Schema: "public", Name: "users", Table: "users", Definition: "CREATE TABLE public.users"},
+		testColumn("users", 1, "id", "integer", true, ""),
+		testColumn("users", 2, "name", "varchar(100)", true, "''::character varying"),
+		{Kind: KindConstraint, Schema: "public", Name: "users_pkey", Table: "users",
+			Definition: "ALTER TABLE public.users ADD CONSTRAINT users_pkey PRIMARY KEY (id)"},
+		{Kind: KindTable, Schema: "public", Name: "orders", Table: "orders", Definition: "CREATE TABLE public.orders"},
+		testColumn("orders", 1, "id", "integer", true, ""),
+		testColumn("orders", 2, "user_id", "integer", false, ""),
+		{Kind: KindConstraint, Schema: "public", Name: "orders_user_fk", Table: "orders",
+			Definition: "ALTER TABLE public.orders ADD CONSTRAINT orders_user_fk FOREIGN KEY (user_id) REFERENCES public.users(id)"},
+		{Kind: KindIndex, Schema: "public", Name: "orders_user_idx", Table: "orders",
+			Definition: "CREATE INDEX orders_user_idx ON public.orders USING btree (user_id)"},
+	}
+
+	require.Empty(t, DiffScript(current, current))
+
+	require.Equal(t, []string{
+		"ALTER TABLE public.users DROP COLUMN legacy",
+		"DROP TABLE public.old",
+		"CREATE TABLE public.orders (\n    id integer NOT NULL,\n    user_id integer\n)",
+		"ALTER TABLE public.users ALTER COLUMN name TYPE varchar(100) USING name::varchar(100)",
+		"ALTER TABLE public.users ALTER COLUMN name SET DEFAULT ''::character varying",
+		"ALTER TABLE public.users ALTER COLUMN name SET NOT NULL",
+		"ALTER TABLE public.orders ADD CONSTRAINT orders_user_fk FOREIGN KEY (user_id) REFERENCES public.users(id)",
+		"CREATE INDEX orders_user_idx ON public.orders USING btree (user_id)",
+	}, DiffScript(current, desired))
+
+	require.Equal(t, []string{
+		"DROP TABLE public.orders",
+		"CREATE TABLE public.old (\n    id integer\n)",
+		"ALTER TABLE public.users ALTER COLUMN name TYPE text USING name::text",
+		"ALTER TABLE public.users ALTER COLUMN name DROP DEFAULT",
+		"ALTER TABLE public.users ALTER COLUMN name DROP NOT NULL",
+		"ALTER TABLE public.users ADD COLUMN legacy text",
+	}, DiffScript(desired, current))
+}
+
+func TestDiffScript_Recreate(t *testing.T) {
+	from := Catalog{
+		{Kind: KindView, Schema: "public", Name: "v", Definition: "CREATE VIEW public.v AS SELECT 1"},
+		{Kind: KindSequence, Schema: "public", Name: "s", Definition: "CREATE SEQUENCE public.s AS bigint INCREMENT BY 1"},
+	}
+	to := Catalog{
+		{Kind: KindView, Schema: "public", Name: "v", Definition: "CREATE VIEW public.v AS SELECT 2"},
+		{Kind: KindSequence, Schema: "public", Name: "s", Definition: "CREATE SEQUENCE public.s AS bigint INCREMENT BY 2"},
+	}
+
+	require.Equal(t, []string{
+		"DROP VIEW public.v",
+		"ALTER SEQUENCE public.s AS bigint INCREMENT BY 2 NO CYCLE",
+		"CREATE VIEW public.v AS SELECT 2",
+	}, DiffScript(from, to))
+}
+
+func TestRenameSchema(t *testing.T) {
+	o := renameSchema(CatalogObject{
+		Kind:       KindColumn,
+		Schema:     "gm_diff_1",
+		Name:       "id",
+		Table:      "users",
+		Definition: "id integer DEFAULT nextval('gm_diff_1.users_id_seq'::regclass)",
+		ColType:    "gm_diff_1.mood",
+		ColDefault: "nextval('gm_diff_1.users_id_seq'::regclass)",
+	}, "gm_diff_1", "public")
+
+	require.Equal(t, "public", o.Schema)
+	require.Equal(t, "id integer DEFAULT nextval('public.users_id_seq'::regclass)", o.Definition)
+	require.Equal(t, "public.mood", o.ColType)
+	require.Equal(t, "nextval('public.users_id_seq'::regclass)", o.ColDefault)
+
+	require.Equal(t, `"user"`, quoteIdent("user"))
+	require.Equal(t, `"Users"`, quoteIdent("Users"))
+	require.Equal(t, "users", quoteIdent("users"))
+}
@@ -0,0 +1,157 @@
+package migdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+const (
+	HistoryGoose   = "goose"
+	HistoryMigrate = "migrate"
+	HistoryFlyway  = "flyway"
+
+	gooseTableName   = "goose_db_version"
+	migrateTableName = "schema_migrations"
+	flywayTableName  = "flyway_schema_history"
+)
+
+// HistoryRecord запись о примененной версии из таблицы другого инструмента.
+// UpTo означает, что применены все версии не выше указанной (golang-migrate хранит только текущую).
+type HistoryRecord struct {
+	Version   string    `db:"version"`
+	AppliedAt time.Time `db:"applied_at"`
+	UpTo      bool
+}
+
+var (
+	ErrDirtyHistory = errors.New("таблица golang-migrate в состоянии dirty")
+
+	tableNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+)
+
+func HistoryTable(source string) (string, error) {
+	switch source {
+	case HistoryGoose:
+		return gooseTableName, nil
+	case HistoryMigrate:
+		return migrateTableName, nil
+	case HistoryFlyway:
+		return flywayTableName, nil
+	}
+
+	return "", fmt.Errorf("неизвестный источник истории миграций: %s", source)
+}
+
+// ReadHistory читает примененные версии из таблицы goose, golang-migrate или Flyway.
+func (b *Pg) ReadHistory(ctx context.Context, source string, table string) ([]HistoryRecord, error) {
+	if !tableNameRe.MatchString(table) {
+		return nil, fmt.Errorf("недопустимое имя таблицы: %s", table)
+	}
+
+	switch source {
+	case HistoryGoose:
+		return b.readGooseHistory(ctx, table)
+	case HistoryMigrate:
+		return b.readMigrateHistory(ctx, table)
+	case HistoryFlyway:
+		return b.readFlywayHistory(ctx, table)
+	}
+
+	return nil, fmt.Errorf("неизвестный источник истории миграций: %s", source)
+}
+
+// В goose каждая строка - применение или откат версии, актуальна последняя по id.
+func (b *Pg) readGooseHistory(ctx context.Context, table string) ([]HistoryRecord, error) {
+	sqlReq := `SELECT version_id::text AS version, applied_at FROM (
+			SELECT DISTINCT ON (version_id) version_id, is_applied, COALESCE(tstamp, now()) AS applied_at
+			FROM ` + table + `
+			WHERE version_id > 0
+			ORDER BY version_id, id DESC
+		) h WHERE is_applied ORDER BY version_id`
+	data := make([]HistoryRecord, 0)
+	if err := b.conn.SelectContext(ctx, &data, sqlReq); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (b *Pg) readMigrateHistory(ctx context.Context, table string) ([]HistoryRecord, error) {
+	var (
+		version int64
+		dirty   bool
+	)
+
+	err := b.conn.QueryRowContext(ctx, "SELECT version, dirty FROM "+table+" LIMIT 1").Scan(&version, &dirty)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return []HistoryRecord{}, nil
+		}
+		return nil, err
+	}
+
+	if dirty {
+		return nil, fmt.Errorf("%w: версия %d", ErrDirtyHistory, version)
+	}
+
+	return []HistoryRecord{{
+		Version:   strconv.FormatInt(version, 10),
+		AppliedAt: time.Now(),
+		UpTo:      true,
+	}}, nil
+}
+
+// Во Flyway версия применена, если ее последняя успешная запись не является отменой.
+func (b *Pg) readFlywayHistory(ctx context.Context, table string) ([]HistoryRecord, error) {
+	sqlReq := `SELECT version, applied_at FROM (
+			SELECT DISTINCT ON (version) version, type, installed_on AS applied_at, installed_rank
+			FROM ` + table + `
+			WHERE success AND version IS NOT NULL
+			ORDER BY version, installed_rank DESC
+		) h WHERE type NOT LIKE 'UNDO%' ORDER BY installed_rank`
+	data := make([]HistoryRecord, 0)
+	if err := b.conn.SelectContext(ctx, &data, sqlReq); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// ImportApplied записывает примененные миграции с исходным временем применения.
+// Возвращает имена добавленных записей, уже существующие записи не изменяются.
+func (b *Pg) ImportApplied(ctx context.Context, list []MigrateInfo) ([]string, error) {
+	const logPrefixImport = "импорт истории миграций:"
+
+	tx, err := b.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	s := "INSERT INTO " + serviceTableName + " (name, status, created_at, updated_at) VALUES($1, $2, $3, $3) " +
+		"ON CONFLICT (name) DO NOTHING"
+
+	imported := make([]string, 0, len(list))
+	for _, item := range list {
+		res, err := tx.ExecContext(ctx, s, item.Name, statusApplied, item.UpdatedAt)
+		if err != nil {
+			b.txRollback(tx, logPrefixImport)
+			return nil, fmt.Errorf("запись миграции %s: %w", item.Name, err)
+		}
+
+		if n, err := res.RowsAffected(); err == nil && n > 0 {
+			imported = append(imported, item.Name)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		b.txRollback(tx, logPrefixImport)
+		return nil, fmt.Errorf("ошибка закрытия транзакции: %w", err)
+	}
+
+	return imported, nil
+}
@@ -4,8 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"net"
+	"net/url"
+	"sort"
 	"time"
 
+	migfile "github.com/dimonk33/sql-migrator/internal/file"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq" // Инициализация драйвера Postgresql
 )
@@ -20,9 +24,9 @@ const (
 )
 
 type Pg struct {
-	connStr string
-	conn    *sqlx.DB
-	logger  Logger
+	param  ConnParam
+	conn   *sqlx.DB
+	logger Logger
 }
 
 type Logger interface {
@@ -44,7 +48,28 @@ type MigrateInfo struct {
 	UpdatedAt time.Time `db:"updated_at"`
 }
 
+type RepeatableInfo struct {
+	Name      string    `db:"name"`
+	Checksum  string    `db:"checksum"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
 func NewPgMigrator(ctx context.Context, dbConn *ConnParam, l Logger) (*Pg, error) {
+	b, err := NewPgReader(ctx, dbConn, l)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = b.Init(ctx); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// NewPgReader подключение без создания служебных таблиц для команд, которые только
+// читают базу: сравнение баз не должно менять схему и брать блокировки на служебной таблице.
+func NewPgReader(ctx context.Context, dbConn *ConnParam, l Logger) (*Pg, error) {
 	connStr := fmt.Sprintf(
 		"host=%s port=%s dbname=%s user=%s password='%s' sslmode=%s",
 		dbConn.Host,
@@ -58,20 +83,15 @@ func NewPgMigrator(ctx context.Context, dbConn *ConnParam, l Logger) (*Pg, error
 	if err != nil {
 		return nil, err
 	}
-	b := &Pg{
-		conn:    c,
-		logger:  l,
-		connStr: connStr,
-	}
-	err = b.initTable(ctx)
-	if err != nil {
-		return nil, err
-	}
-
-	return b, nil
+	return &Pg{
+		param:  *dbConn,
+		conn:   c,
+		logger: l,
+	}, nil
 }
 
-func (b *Pg) initTable(ctx context.Context) error {
+// Init создает служебные таблицы, если их еще нет.
+func (b *Pg) Init(ctx context.Context) error {
 	const logInitPrefix = "создание служебных таблиц: "
 
 	var (
@@ -84,51 +104,74 @@ func (b *Pg) initTable(ctx context.Context) error {
 		return err
 	}
 
-	_, err = tx.ExecContext(ctx,
-		`DO $$
-			BEGIN
-				IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = '`+enumTableName+`') THEN
-					CREATE TYPE `+enumTableName+` AS ENUM('processing', 'applied');
-				END IF;
-			END
-			$$;`)
-	if err != nil {
-		b.txRollback(tx, logInitPrefix)
-		return err
+	for _, s := range initStatements() {
+		if _, err = tx.ExecContext(ctx, s); err != nil {
+			b.txRollback(tx, logInitPrefix)
+			return err
+		}
 	}
 
-	_, err = tx.ExecContext(ctx,
-		`CREATE TABLE IF NOT EXISTS `+serviceTableName+`(
-			    id SERIAL PRIMARY KEY,
-				name varchar(255) NOT NULL,
-				status `+enumTableName+` NOT NULL,
-				created_at timestamp NOT NULL default now(),
-				updated_at timestamp NOT NULL default now()
-			)`,
-	)
+	err = tx.Commit()
 	if err != nil {
-		b.txRollback(tx, logInitPrefix)
 		return err
 	}
 
-	_, err = tx.ExecContext(ctx,
-		"CREATE UNIQUE INDEX IF NOT EXISTS name_uniq_idx ON "+serviceTableName+"(name);",
-	)
-	if err != nil {
-		b.txRollback(tx, logInitPrefix)
-		return err
+	return nil
+}
+
+// ConnEnv параметры подключения в виде переменных среды libpq для дочерних процессов,
+// чтобы учетные данные не попадали в исходники и аргументы командной строки.
+func (b *Pg) ConnEnv() []string {
+	return b.param.Env()
+}
+
+// Env параметры подключения в виде переменных среды libpq.
+func (p ConnParam) Env() []string {
+	return []string{
+		"PGHOST=" + p.Host,
+		"PGPORT=" + p.Port,
+		"PGDATABASE=" + p.Name,
+		"PGUSER=" + p.User,
+		"PGPASSWORD=" + p.Password,
+		"PGSSLMODE=" + p.SSL,
 	}
+}
 
-	err = tx.Commit()
-	if err != nil {
-		return err
+// ConnURL параметры подключения в виде URL для shell миграций.
+func (b *Pg) ConnURL() string {
+	return b.param.URL()
+}
+
+// URL параметры подключения в виде postgres:// URL.
+func (p ConnParam) URL() string {
+	host := p.Host
+	if p.Port != "" {
+		host = net.JoinHostPort(p.Host, p.Port)
 	}
 
-	return nil
+	u := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(p.User, p.Password),
+		Host:   host,
+		Path:   "/" + p.Name,
+	}
+	if p.SSL != "" {
+		u.RawQuery = url.Values{"sslmode": {p.SSL}}.Encode()
+	}
+
+	return u.String()
 }
 
-func (b *Pg) GetConnString() string {
-	return b.connStr
+// HistorySQL запрос, которым go миграция в своей транзакции записывает свое применение
+// или удаляет запись при откате, $1 - имя миграции.
+func (b *Pg) HistorySQL(up bool) string {
+	if up {
+		// Запись могла остаться со статусом processing после прерванного запуска
+		return "INSERT INTO " + serviceTableName + " (name, status) VALUES($1, '" + statusApplied + "') " +
+			"ON CONFLICT (name) DO UPDATE SET status = '" + statusApplied + "', updated_at = now()"
+	}
+
+	return "DELETE FROM " + serviceTableName + " WHERE name = $1"
 }
 
 func (b *Pg) Lock(ctx context.Context, sign string) bool {
@@ -172,19 +215,56 @@ func (b *Pg) Find(ctx context.Context, name string) (int, error) {
 }
 
 func (b *Pg) FindLast(ctx context.Context) (string, error) {
-	sqlReq := "SELECT name FROM " + serviceTableName + " WHERE status = 'applied' ORDER BY created_at DESC LIMIT 1"
-	var name string
-	err := b.conn.GetContext(ctx, &name, sqlReq)
+	data, err := b.FindAllApplied(ctx)
 	if err != nil {
-		return name, err
+		return "", err
+	}
+
+	if len(data) == 0 {
+		return "", sql.ErrNoRows
 	}
 
-	return name, nil
+	return data[0].Name, nil
 }
 
+// FindAllApplied возвращает примененные миграции по убыванию версии.
 func (b *Pg) FindAllApplied(ctx context.Context) ([]MigrateInfo, error) {
-	sqlReq := "SELECT name, updated_at FROM " + serviceTableName + " WHERE status = 'applied' ORDER BY created_at DESC"
-	data := make([]MigrateInfo, 1)
+	sqlReq := "SELECT name, updated_at FROM " + serviceTableName +
+		" WHERE status = 'applied' AND NOT repeatable ORDER BY created_at DESC"
+	data := make([]MigrateInfo, 0)
+	err := b.conn.SelectContext(ctx, &data, sqlReq)
+	if err != nil {
+		return data, err
+	}
+
+	versions := make(map[string]migfile.Version, len(data))
+	for _, item := range data {
+		v, err := migfile.ParseVersion(item.Name)
+		if err != nil {
+			return nil, err
+		}
+		versions[item.Name] = v
+	}
+
+	sort.SliceStable(data, func(i, j int) bool {
+		return versions[data[i].Name].Compare(versions[data[j].Name]) > 0
+	})
+
+	return data, nil
+}
+
+// HistoryExists есть ли в базе таблица истории миграций.
+func (b *Pg) HistoryExists(ctx context.Context) (bool, error) {
+	var exists bool
+	err := b.conn.GetContext(ctx, &exists, "SELECT to_regclass($1) IS NOT NULL", serviceTableName)
+
+	return exists, err
+}
+
+func (b *Pg) FindAllRepeatable(ctx context.Context) ([]RepeatableInfo, error) {
+	sqlReq := "SELECT name, checksum, updated_at FROM " + serviceTableName +
+		" WHERE status = 'applied' AND repeatable ORDER BY name"
+	data := make([]RepeatableInfo, 0)
 	err := b.conn.SelectContext(ctx, &data, sqlReq)
 	if err != nil {
 		return data, err
@@ -265,8 +345,76 @@ func (b *Pg) RevertTx(ctx context.Context, name string, sqlPool []string) error
 	return nil
 }
 
+// ApplyNoTx применение миграции вне транзакции, запись о миграции создается до выполнения запросов.
+func (b *Pg) ApplyNoTx(ctx context.Context, name string, sqlPool []string) error {
+	if err := b.Create(ctx, name); err != nil {
+		return fmt.Errorf("создание записи в базе: %w", err)
+	}
+
+	for i, s := range sqlPool {
+		if _, err := b.conn.ExecContext(ctx, s); err != nil {
+			b.deleteMigrate(ctx, name)
+			return fmt.Errorf("выполнение запроса %d: %w", i, err)
+		}
+	}
+
+	if err := b.SetApplied(ctx, name); err != nil {
+		return fmt.Errorf("изменение статуса миграции: %w", err)
+	}
+
+	return nil
+}
+
+func (b *Pg) RevertNoTx(ctx context.Context, name string, sqlPool []string) error {
+	for i, s := range sqlPool {
+		if _, err := b.conn.ExecContext(ctx, s); err != nil {
+			return fmt.Errorf("выполнение запроса %d: %w", i, err)
+		}
+	}
+
+	if err := b.Delete(ctx, name); err != nil {
+		return fmt.Errorf("удаление миграции: %w", err)
+	}
+
+	return nil
+}
+
+func (b *Pg) ApplyRepeatableTx(ctx context.Context, name string, checksum string, sqlPool []string) error {
+	const logPrefixRepeatMigration = "применение повторяемой миграции:"
+
+	tx, err := b.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	for i, s := range sqlPool {
+		_, err = tx.ExecContext(ctx, s)
+		if err != nil {
+			b.txRollback(tx, logPrefixRepeatMigration)
+			return fmt.Errorf("выполнение запроса %d: %w", i, err)
+		}
+	}
+
+	s := "INSERT INTO " + serviceTableName + " (name, status, repeatable, checksum) VALUES($1, $2, true, $3) " +
+		"ON CONFLICT (name) DO UPDATE SET status = $2, checksum = $3, updated_at = now()"
+	_, err = tx.ExecContext(ctx, s, name, statusApplied, checksum)
+	if err != nil {
+		b.txRollback(tx, logPrefixRepeatMigration)
+		return fmt.Errorf("сохранение контрольной суммы: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		b.txRollback(tx, logPrefixRepeatMigration)
+		return fmt.Errorf("ошибка закрытия транзакции: %w", err)
+	}
+
+	return nil
+}
+
 func (b *Pg) Create(ctx context.Context, name string) error {
-	sqlReq := "INSERT INTO " + serviceTableName + " (name, status) VALUES($1, $2)"
+	sqlReq := "INSERT INTO " + serviceTableName + " (name, status) VALUES($1, $2) " +
+		"ON CONFLICT (name) DO UPDATE SET status = $2, updated_at = now()"
 	_, err := b.conn.ExecContext(ctx, sqlReq, name, statusProcessing)
 	return err
 }
@@ -0,0 +1,35 @@
+package migdb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnParam_Env(t *testing.T) {
+	p := ConnParam{Host: "db", Port: "5433", Name: "app", User: "gm", Password: "p 'q'", SSL: "require"}
+
+	require.Equal(t, []string{
+		"PGHOST=db",
+		"PGPORT=5433",
+		"PGDATABASE=app",
+		"PGUSER=gm",
+		"PGPASSWORD=p 'q'",
+		"PGSSLMODE=require",
+	}, p.Env())
+}
+
+func TestConnParam_URL(t *testing.T) {
+	p := ConnParam{Host: "db", Port: "5433", Name: "app", User: "gm", Password: "p@ss/w", SSL: "require"}
+	require.Equal(t, "postgres://gm:p%40ss%2Fw@db:5433/app?sslmode=require", p.URL())
+
+	p = ConnParam{Host: "db", Name: "app", User: "gm"}
+	require.Equal(t, "postgres://gm:@db/app", p.URL())
+}
+
+func TestPg_HistorySQL(t *testing.T) {
+	b := &Pg{}
+	require.Equal(t, "INSERT INTO gomigrate_info (name, status) VALUES($1, 'applied') "+
+		"ON CONFLICT (name) DO UPDATE SET status = 'applied', updated_at = now()", b.HistorySQL(true))
+	require.Equal(t, "DELETE FROM gomigrate_info WHERE name = $1", b.HistorySQL(false))
+}
@@ -0,0 +1,73 @@
+package migdb
+
+import (
+	"io"
+	"sort"
+	"strings"
+)
+
+const schemaHeader = "-- Схема базы данных, файл создан командой gomigrator schema dump.\n" +
+	"-- Не редактируйте вручную.\n"
+
+// WriteSchema записывает снимок схемы в виде sql скрипта. Порядок объектов детерминирован:
+// расширения, типы, последовательности, таблицы со столбцами, ограничения (внешние ключи последними),
+// индексы, представления, функции.
+func WriteSchema(w io.Writer, c Catalog) error {
+	sortCatalog(c)
+
+	columns := make(map[string][]string)
+	byKind := make(map[string][]CatalogObject)
+	for _, o := range c {
+		if o.Kind == KindColumn {
+			key := o.Schema + "." + o.Table
+			columns[key] = append(columns[key], o.Definition)
+			continue
+		}
+		byKind[o.Kind] = append(byKind[o.Kind], o)
+	}
+
+	constraints := byKind[KindConstraint]
+	sort.SliceStable(constraints, func(i, j int) bool {
+		return !isForeignKey(constraints[i]) && isForeignKey(constraints[j])
+	})
+
+	builder := strings.Builder{}
+	builder.WriteString(schemaHeader)
+
+	for _, kind := range []string{KindExtension, KindType, KindSequence, KindTable, KindConstraint, KindIndex, KindView, KindFunction} {
+		list := byKind[kind]
+		if len(list) == 0 {
+			continue
+		}
+
+		builder.WriteString("\n-- " + kind + "\n")
+		for i, o := range list {
+			if i > 0 && (kind == KindTable || kind == KindView || kind == KindFunction) {
+				builder.WriteString("\n")
+			}
+
+			def := o.Definition
+			if kind == KindTable {
+				def += " (" + tableColumns(columns[o.Schema+"."+o.Name]) + ")"
+			}
+			builder.WriteString(strings.TrimRight(def, "; \n"))
+			builder.WriteString(";\n")
+		}
+	}
+
+	_, err := io.WriteString(w, builder.String())
+
+	return err
+}
+
+func tableColumns(list []string) string {
+	if len(list) == 0 {
+		return ""
+	}
+
+	return "\n    " + strings.Join(list, ",\n    ") + "\n"
+}
+
+func isForeignKey(o CatalogObject) bool {
+	return strings.Contains(o.Definition, " FOREIGN KEY ")
+}
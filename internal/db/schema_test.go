@@ -0,0 +1,47 @@
+package migdb
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteSchema(t *testing.T) {
+	c := Catalog{
+		{Kind: KindConstraint, Schema: "public", Name: "orders_user_fk", Table: "orders",
+			Definition: "ALTER TABLE public.orders ADD CONSTRAINT orders_user_fk FOREIGN KEY (user_id) REFERENCES users(id)"},
+		{Kind: KindColumn, Schema: "public", Name: "user_id", Table: "orders", Position: 2, Definition: "user_id integer"},
+		{Kind: KindColumn, Schema: "public", Name: "id", Table: "orders", Position: 1, Definition: "id integer NOT NULL"},
+		{Kind: KindTable, Schema: "public", Name: "orders", Table: "orders", Definition: "CREATE TABLE public.orders"},
+		{Kind: KindTable, Schema: "public", Name: "empty", Table: "empty", Definition: "CREATE TABLE public.empty"},
+		{Kind: KindConstraint, Schema: "public", Name: "orders_pkey", Table: "orders",
+			Definition: "ALTER TABLE public.orders ADD CONSTRAINT orders_pkey PRIMARY KEY (id)"},
+		{Kind: KindExtension, Name: "pgcrypto", Definition: "CREATE EXTENSION IF NOT EXISTS pgcrypto WITH SCHEMA public"},
+		{Kind: KindView, Schema: "public", Name: "ids", Definition: "CREATE VIEW public.ids AS\n SELECT id\n   FROM orders"},
+	}
+
+	builder := strings.Builder{}
+	require.NoError(t, WriteSchema(&builder, c))
+	require.Equal(t, schemaHeader+`
+-- extension
+CREATE EXTENSION IF NOT EXISTS pgcrypto WITH SCHEMA public;
+
+-- table
+CREATE TABLE public.empty ();
+
+CREATE TABLE public.orders (
+    id integer NOT NULL,
+    user_id integer
+);
+
+-- constraint
+ALTER TABLE public.orders ADD CONSTRAINT orders_pkey PRIMARY KEY (id);
+ALTER TABLE public.orders ADD CONSTRAINT orders_user_fk FOREIGN KEY (user_id) REFERENCES users(id);
+
+-- view
+CREATE VIEW public.ids AS
+ SELECT id
+   FROM orders;
+`, builder.String())
+}
@@ -0,0 +1,121 @@
+package migdb
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Без ON_ERROR_STOP psql продолжает выполнять скрипт после ошибки, и запись о применении
+// миграции попадает в историю, даже если ее запросы не выполнились.
+const psqlStopOnError = "\\set ON_ERROR_STOP on\n"
+
+// Запросы создания служебных таблиц.
+func initStatements() []string {
+	return []string{
+		`DO $$
+			BEGIN
+				IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = '` + enumTableName + `') THEN
+					CREATE TYPE ` + enumTableName + ` AS ENUM('processing', 'applied');
+				END IF;
+			END
+			$$`,
+		`CREATE TABLE IF NOT EXISTS ` + serviceTableName + `(
+			    id SERIAL PRIMARY KEY,
+				name varchar(255) NOT NULL,
+				status ` + enumTableName + ` NOT NULL,
+				created_at timestamp NOT NULL default now(),
+				updated_at timestamp NOT NULL default now()
+			)`,
+		// ALTER TABLE берет эксклюзивную блокировку таблицы, поэтому выполняется,
+		// только если колонок повторяемых миграций еще нет
+		`DO $$
+			BEGIN
+				IF (SELECT count(*) FROM pg_attribute
+					WHERE attrelid = '` + serviceTableName + `'::regclass
+						AND attname IN ('repeatable', 'checksum') AND NOT attisdropped) < 2 THEN
+					ALTER TABLE ` + serviceTableName + `
+						ADD COLUMN IF NOT EXISTS repeatable boolean NOT NULL default false,
+						ADD COLUMN IF NOT EXISTS checksum varchar(64) NOT NULL default '';
+				END IF;
+			END
+			$$`,
+		"CREATE UNIQUE INDEX IF NOT EXISTS name_uniq_idx ON " + serviceTableName + "(name)",
+	}
+}
+
+// WriteInitScript записывает в скрипт создание служебных таблиц.
+func WriteInitScript(w io.Writer) error {
+	builder := strings.Builder{}
+	builder.WriteString(psqlStopOnError)
+	builder.WriteString("-- Служебные таблицы\nBEGIN;\n")
+	for _, s := range initStatements() {
+		builder.WriteString(s)
+		builder.WriteString(";\n")
+	}
+	builder.WriteString("COMMIT;\n")
+
+	_, err := io.WriteString(w, builder.String())
+
+	return err
+}
+
+// WriteApplyScript записывает в скрипт запросы миграции и запись о ее применении.
+// Миграция без транзакции записывается без BEGIN/COMMIT. Блок начинается с ON_ERROR_STOP,
+// поэтому его можно выполнить и отдельно от остального скрипта.
+func WriteApplyScript(w io.Writer, name string, sqlPool []string, noTx bool) error {
+	builder := strings.Builder{}
+	builder.WriteString("\n" + psqlStopOnError)
+	builder.WriteString(fmt.Sprintf("-- Миграция: %s\n", name))
+
+	if !noTx {
+		builder.WriteString("BEGIN;\n")
+	}
+
+	for _, s := range sqlPool {
+		builder.WriteString(strings.TrimRight(strings.TrimSpace(s), ";"))
+		builder.WriteString(";\n")
+	}
+
+	builder.WriteString(fmt.Sprintf(
+		"INSERT INTO %s (name, status) VALUES ('%s', '%s');\n",
+		serviceTableName,
+		strings.ReplaceAll(name, "'", "''"),
+		statusApplied,
+	))
+
+	if !noTx {
+		builder.WriteString("COMMIT;\n")
+	}
+
+	_, err := io.WriteString(w, builder.String())
+
+	return err
+}
+
+// WriteRepeatableScript записывает в скрипт запросы повторяемой миграции и ее контрольную
+// сумму в одной транзакции, как ApplyRepeatableTx.
+func WriteRepeatableScript(w io.Writer, name string, checksum string, sqlPool []string) error {
+	builder := strings.Builder{}
+	builder.WriteString("\n" + psqlStopOnError)
+	builder.WriteString(fmt.Sprintf("-- Повторяемая миграция: %s\nBEGIN;\n", name))
+
+	for _, s := range sqlPool {
+		builder.WriteString(strings.TrimRight(strings.TrimSpace(s), ";"))
+		builder.WriteString(";\n")
+	}
+
+	builder.WriteString(fmt.Sprintf(
+		"INSERT INTO %s (name, status, repeatable, checksum) VALUES ('%s', '%s', true, '%s') "+
+			"ON CONFLICT (name) DO UPDATE SET status = EXCLUDED.status, checksum = EXCLUDED.checksum, updated_at = now();\n",
+		serviceTableName,
+		strings.ReplaceAll(name, "'", "''"),
+		statusApplied,
+		strings.ReplaceAll(checksum, "'", "''"),
+	))
+	builder.WriteString("COMMIT;\n")
+
+	_, err := io.WriteString(w, builder.String())
+
+	return err
+}
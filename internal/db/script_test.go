@@ -0,0 +1,77 @@
+package migdb
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteApplyScript(t *testing.T) {
+	tests := []struct {
+		name    string
+		mname   string
+		sqlPool []string
+		noTx    bool
+		want    string
+	}{
+		{
+			name:    "transaction",
+			mname:   "00001_it's.sql",
+			sqlPool: []string{"CREATE TABLE t (id int)", " DROP VIEW v; "},
+			want: `
+\set ON_ERROR_STOP on
+-- Миграция: 00001_it's.sql
+BEGIN;
+CREATE TABLE t (id int);
+DROP VIEW v;
+INSERT INTO gomigrate_info (name, status) VALUES ('00001_it''s.sql', 'applied');
+COMMIT;
+`,
+		},
+		{
+			name:    "without transaction",
+			mname:   "00002_idx.sql",
+			sqlPool: []string{"CREATE INDEX CONCURRENTLY i ON t (id)"},
+			noTx:    true,
+			want: `
+\set ON_ERROR_STOP on
+-- Миграция: 00002_idx.sql
+CREATE INDEX CONCURRENTLY i ON t (id);
+INSERT INTO gomigrate_info (name, status) VALUES ('00002_idx.sql', 'applied');
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := strings.Builder{}
+			require.NoError(t, WriteApplyScript(&builder, tt.mname, tt.sqlPool, tt.noTx))
+			require.Equal(t, tt.want, builder.String())
+		})
+	}
+}
+
+func TestWriteInitScript(t *testing.T) {
+	builder := strings.Builder{}
+	require.NoError(t, WriteInitScript(&builder))
+
+	script := builder.String()
+	require.True(t, strings.HasPrefix(script, "\\set ON_ERROR_STOP on\n-- Служебные таблицы\nBEGIN;\n"))
+	require.True(t, strings.HasSuffix(script, "COMMIT;\n"))
+	require.Contains(t, script, "CREATE TABLE IF NOT EXISTS "+serviceTableName)
+	require.Contains(t, script, "IF (SELECT count(*) FROM pg_attribute")
+}
+
+func TestWriteRepeatableScript(t *testing.T) {
+	builder := strings.Builder{}
+	require.NoError(t, WriteRepeatableScript(&builder, "R__views.sql", "abc", []string{"CREATE OR REPLACE VIEW v AS SELECT 1"}))
+	require.Equal(t, `
+\set ON_ERROR_STOP on
+-- Повторяемая миграция: R__views.sql
+BEGIN;
+CREATE OR REPLACE VIEW v AS SELECT 1;
+INSERT INTO gomigrate_info (name, status, repeatable, checksum) VALUES ('R__views.sql', 'applied', true, 'abc') `+
+		`ON CONFLICT (name) DO UPDATE SET status = EXCLUDED.status, checksum = EXCLUDED.checksum, updated_at = now();
+COMMIT;
+`, builder.String())
+}
@@ -0,0 +1,111 @@
+package migdb
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	VerifyUp   = "up"
+	VerifyDown = "down"
+
+	verifySavepoint     = "gm_verify"
+	verifyDownSavepoint = "gm_verify_down"
+)
+
+// VerifyItem запросы миграции для проверки.
+type VerifyItem struct {
+	Name string
+	Up   []string
+	Down []string
+}
+
+// VerifyResult результат проверки миграции, Err - ошибка *StatementError.
+type VerifyResult struct {
+	Name   string
+	NoDown bool
+	Err    error
+}
+
+// StatementError ошибка выполнения запроса миграции.
+type StatementError struct {
+	Migration string
+	Direction string
+	Index     int
+	Statement string
+	Err       error
+}
+
+func (e *StatementError) Error() string {
+	return fmt.Sprintf("миграция %s, %s, запрос %d: %v", e.Migration, e.Direction, e.Index, e.Err)
+}
+
+func (e *StatementError) Unwrap() error {
+	return e.Err
+}
+
+// VerifyTx выполняет Up и Down части миграций в транзакции, которая всегда откатывается.
+// Каждая миграция выполняется в точке сохранения: после ошибки проверка продолжается
+// со следующей миграции. После проверки Down восстанавливается состояние после Up,
+// чтобы следующие миграции выполнялись на той схеме, для которой написаны.
+func (b *Pg) VerifyTx(ctx context.Context, items []VerifyItem) ([]VerifyResult, error) {
+	const logPrefixVerifyMigration = "проверка миграций:"
+
+	tx, err := b.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer b.txRollback(tx, logPrefixVerifyMigration)
+
+	exec := func(s string) error {
+		_, err := tx.ExecContext(ctx, s)
+		return err
+	}
+
+	run := func(item VerifyItem, dir string, sqlPool []string) error {
+		for i, s := range sqlPool {
+			if err := exec(s); err != nil {
+				return &StatementError{Migration: item.Name, Direction: dir, Index: i, Statement: s, Err: err}
+			}
+		}
+		return nil
+	}
+
+	out := make([]VerifyResult, 0, len(items))
+	for _, item := range items {
+		res := VerifyResult{Name: item.Name, NoDown: len(item.Down) == 0}
+
+		if err = exec("SAVEPOINT " + verifySavepoint); err != nil {
+			return nil, fmt.Errorf("создание точки сохранения: %w", err)
+		}
+
+		if res.Err = run(item, VerifyUp, item.Up); res.Err != nil {
+			if err = exec("ROLLBACK TO SAVEPOINT " + verifySavepoint); err != nil {
+				return nil, fmt.Errorf("откат к точке сохранения: %w", err)
+			}
+			out = append(out, res)
+			continue
+		}
+
+		if !res.NoDown {
+			if err = exec("SAVEPOINT " + verifyDownSavepoint); err != nil {
+				return nil, fmt.Errorf("создание точки сохранения: %w", err)
+			}
+
+			res.Err = run(item, VerifyDown, item.Down)
+
+			if err = exec("ROLLBACK TO SAVEPOINT " + verifyDownSavepoint); err != nil {
+				return nil, fmt.Errorf("откат к точке сохранения: %w", err)
+			}
+		}
+
+		if err = exec("RELEASE SAVEPOINT " + verifySavepoint); err != nil {
+			return nil, fmt.Errorf("освобождение точки сохранения: %w", err)
+		}
+
+		out = append(out, res)
+	}
+
+	return out, nil
+}
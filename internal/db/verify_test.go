@@ -0,0 +1,26 @@
+package migdb
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatementError(t *testing.T) {
+	cause := errors.New(`relation "users" does not exist`)
+	err := error(&StatementError{
+		Migration: "00002_users.sql",
+		Direction: VerifyDown,
+		Index:     1,
+		Statement: "DROP TABLE users",
+		Err:       cause,
+	})
+
+	require.Equal(t, `миграция 00002_users.sql, down, запрос 1: relation "users" does not exist`, err.Error())
+	require.ErrorIs(t, err, cause)
+
+	var stmtErr *StatementError
+	require.ErrorAs(t, err, &stmtErr)
+	require.Equal(t, "DROP TABLE users", stmtErr.Statement)
+}
@@ -0,0 +1,330 @@
+package executer
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	migfile "github.com/dimonk33/sql-migrator/internal/file"
+)
+
+const (
+	sqlCommentPrefix = "--"
+
+	gooseUpID             = "-- +goose Up"
+	gooseDownID           = "-- +goose Down"
+	gooseStatementBeginID = "-- +goose StatementBegin"
+	gooseStatementEndID   = "-- +goose StatementEnd"
+	gooseNoTransactionID  = "-- +goose NO TRANSACTION"
+
+	// Настройка Flyway в файле <миграция>.sql.conf.
+	flywayConfSuffix = ".conf"
+	flywayNoTxOption = "executeInTransaction=false"
+)
+
+// SQLSection запросы миграции для одного направления.
+type SQLSection struct {
+	Statements []string
+	NoTx       bool
+}
+
+// SQLDialect разметка sql миграций, совместимая с другими инструментами.
+type SQLDialect interface {
+	Match(name string, content string) bool
+	Section(path string, content string, dir int) (SQLSection, error)
+}
+
+var sqlDialects = []SQLDialect{
+	GooseDialect{},
+	FlywayDialect{},
+}
+
+func findSQLDialect(name string, content string) SQLDialect {
+	for _, d := range sqlDialects {
+		if d.Match(name, content) {
+			return d
+		}
+	}
+
+	return NativeDialect{}
+}
+
+// NativeDialect собственная разметка: -- ===gm Up=== / -- ===gm Down===.
+type NativeDialect struct{}
+
+func (NativeDialect) Match(_ string, content string) bool {
+	return strings.Contains(content, migfile.SQLUpPartID)
+}
+
+func (NativeDialect) Section(path string, content string, dir int) (SQLSection, error) {
+	text, err := parseNativeFile(path, content, dir)
+	if err != nil {
+		return SQLSection{}, err
+	}
+
+	return SQLSection{
+		Statements: SplitStatements(text),
+		NoTx:       hasMarker(content, migfile.SQLNoTransactionID),
+	}, nil
+}
+
+// GooseDialect разметка goose: -- +goose Up / Down / StatementBegin / StatementEnd / NO TRANSACTION.
+type GooseDialect struct{}
+
+func (GooseDialect) Match(_ string, content string) bool {
+	return hasMarker(content, gooseUpID)
+}
+
+func (GooseDialect) Section(_ string, content string, dir int) (SQLSection, error) {
+	var want string
+	switch dir {
+	case UpDirection:
+		want = gooseUpID
+	case DownDirection:
+		want = gooseDownID
+	default:
+		return SQLSection{}, ErrWrongDirection
+	}
+
+	builder := strings.Builder{}
+	current := ""
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		if strings.EqualFold(trimmed, gooseUpID) || strings.EqualFold(trimmed, gooseDownID) {
+			current = trimmed
+			continue
+		}
+
+		if strings.EqualFold(current, want) {
+			builder.WriteString(line)
+			builder.WriteString("\n")
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return SQLSection{}, err
+	}
+
+	return SQLSection{
+		Statements: splitBlocks(builder.String(), gooseStatementBeginID, gooseStatementEndID, splitSQL),
+		NoTx:       hasMarker(content, gooseNoTransactionID),
+	}, nil
+}
+
+// FlywayDialect файлы Flyway: V<версия>__<описание>.sql и отменяющий U<версия>__<описание>.sql.
+type FlywayDialect struct{}
+
+func (FlywayDialect) Match(name string, _ string) bool {
+	return migfile.IsFlywayVersioned(name)
+}
+
+func (FlywayDialect) Section(path string, content string, dir int) (SQLSection, error) {
+	switch dir {
+	case UpDirection:
+	case DownDirection:
+		path = migfile.FlywayUndoPath(path)
+
+		undoContent, err := os.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return SQLSection{}, ErrWrongFileFormat
+			}
+			return SQLSection{}, fmt.Errorf("ошибка открытия файла: %w", err)
+		}
+		content = string(undoContent)
+	default:
+		return SQLSection{}, ErrWrongDirection
+	}
+
+	noTx, err := flywayNoTx(path)
+	if err != nil {
+		return SQLSection{}, err
+	}
+
+	return SQLSection{
+		Statements: splitSQL(content),
+		NoTx:       noTx,
+	}, nil
+}
+
+func flywayNoTx(path string) (bool, error) {
+	conf, err := os.ReadFile(path + flywayConfSuffix)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("ошибка открытия файла настроек: %w", err)
+	}
+
+	for _, line := range strings.Split(string(conf), "\n") {
+		if strings.ReplaceAll(strings.TrimSpace(line), " ", "") == flywayNoTxOption {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func hasMarker(content string, marker string) bool {
+	for _, line := range strings.Split(content, "\n") {
+		if strings.EqualFold(strings.TrimSpace(line), marker) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// splitBlocks выделяет блоки между begin и end в отдельные запросы,
+// остальной текст разбирается функцией split.
+func splitBlocks(text string, begin string, end string, split func(string) []string) []string {
+	out := make([]string, 0)
+	outside := strings.Builder{}
+	block := strings.Builder{}
+	inBlock := false
+
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r", ""), "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case !inBlock && strings.EqualFold(trimmed, begin):
+			out = append(out, split(outside.String())...)
+			outside.Reset()
+			inBlock = true
+		case inBlock && strings.EqualFold(trimmed, end):
+			if s := strings.TrimSpace(block.String()); s != "" {
+				out = append(out, s)
+			}
+			block.Reset()
+			inBlock = false
+		case inBlock:
+			block.WriteString(line)
+			block.WriteString("\n")
+		default:
+			outside.WriteString(line)
+			outside.WriteString("\n")
+		}
+	}
+
+	out = append(out, split(outside.String())...)
+
+	return out
+}
+
+// splitSQL разделяет текст на запросы по ";" с учетом строк,
+// идентификаторов в кавычках, $$ блоков и комментариев.
+func splitSQL(text string) []string {
+	out := make([]string, 0)
+	stmt := strings.Builder{}
+
+	flush := func() {
+		if s := strings.TrimSpace(stmt.String()); s != "" {
+			out = append(out, s)
+		}
+		stmt.Reset()
+	}
+
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+
+		switch {
+		case c == '-' && strings.HasPrefix(text[i:], sqlCommentPrefix):
+			n := strings.IndexByte(text[i:], '\n')
+			if n == -1 {
+				i = len(text)
+				continue
+			}
+			i += n
+			stmt.WriteByte('\n')
+		case c == '/' && strings.HasPrefix(text[i:], "/*"):
+			n := strings.Index(text[i+2:], "*/")
+			if n == -1 {
+				i = len(text)
+				continue
+			}
+			i += n + 3
+			stmt.WriteByte(' ')
+		case c == '\'' || c == '"':
+			n := strings.IndexByte(text[i+1:], c)
+			if n == -1 {
+				stmt.WriteString(text[i:])
+				i = len(text)
+				continue
+			}
+			stmt.WriteString(text[i : i+n+2])
+			i += n + 1
+		case c == '$':
+			tag := dollarTag(text[i:])
+			if tag == "" {
+				stmt.WriteByte(c)
+				continue
+			}
+			n := strings.Index(text[i+len(tag):], tag)
+			if n == -1 {
+				stmt.WriteString(text[i:])
+				i = len(text)
+				continue
+			}
+			stmt.WriteString(text[i : i+len(tag)+n+len(tag)])
+			i += len(tag) + n + len(tag) - 1
+		case c == ';':
+			flush()
+		default:
+			stmt.WriteByte(c)
+		}
+	}
+
+	flush()
+
+	return out
+}
+
+// dollarTag возвращает открывающий тег $tag$, если текст с него начинается.
+func dollarTag(text string) string {
+	for i := 1; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '$':
+			return text[:i+1]
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 1 && c >= '0' && c <= '9':
+		default:
+			return ""
+		}
+	}
+
+	return ""
+}
+
+// SplitStatements разделяет sql текст на запросы, блоки StatementBegin/StatementEnd
+// выполняются одним запросом.
+func SplitStatements(text string) []string {
+	return splitBlocks(text, migfile.SQLStatementBeginID, migfile.SQLStatementEndID, splitSQL)
+}
+
+// JoinStatements собирает запросы в текст части собственной разметки миграции.
+// Многострочные запросы и запросы с ";" внутри, например функции и DO блоки,
+// оборачиваются в StatementBegin/StatementEnd и выполняются как есть.
+// Комментарии для ручной правки остаются без разделителя.
+func JoinStatements(list []string) string {
+	builder := strings.Builder{}
+	for i, s := range list {
+		if i > 0 {
+			builder.WriteString("\n")
+		}
+
+		switch {
+		case strings.HasPrefix(s, sqlCommentPrefix):
+			builder.WriteString(s)
+		case strings.ContainsAny(s, ";\n"):
+			builder.WriteString(migfile.SQLStatementBeginID + "\n" + s + "\n" + migfile.SQLStatementEndID)
+		default:
+			builder.WriteString(s + ";")
+		}
+	}
+
+	return builder.String()
+}
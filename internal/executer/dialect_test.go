@@ -0,0 +1,201 @@
+package executer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	migdb "github.com/dimonk33/sql-migrator/internal/db"
+	migfile "github.com/dimonk33/sql-migrator/internal/file"
+	"github.com/dimonk33/sql-migrator/internal/logger"
+	"github.com/stretchr/testify/require"
+)
+
+const testGooseContent = `-- +goose NO TRANSACTION
+-- +goose Up
+-- создание таблицы
+CREATE TABLE users (
+    id int,
+    name text DEFAULT 'a;b'
+);
+-- +goose StatementBegin
+CREATE FUNCTION one() RETURNS int AS $$
+BEGIN
+    RETURN 1;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+CREATE INDEX CONCURRENTLY users_name_idx ON users (name);
+
+-- +goose Down
+DROP FUNCTION one();
+DROP TABLE users;
+`
+
+func TestGooseDialect_Section(t *testing.T) {
+	d := findSQLDialect("00001_users.sql", testGooseContent)
+	require.IsType(t, GooseDialect{}, d)
+
+	up, err := d.Section("00001_users.sql", testGooseContent, UpDirection)
+	require.NoError(t, err)
+	require.True(t, up.NoTx)
+	require.Equal(t, []string{
+		"CREATE TABLE users (\n    id int,\n    name text DEFAULT 'a;b'\n)",
+		"CREATE FUNCTION one() RETURNS int AS $$\nBEGIN\n    RETURN 1;\nEND;\n$$ LANGUAGE plpgsql;",
+		"CREATE INDEX CONCURRENTLY users_name_idx ON users (name)",
+	}, up.Statements)
+
+	down, err := d.Section("00001_users.sql", testGooseContent, DownDirection)
+	require.NoError(t, err)
+	require.Equal(t, []string{"DROP FUNCTION one()", "DROP TABLE users"}, down.Statements)
+
+	_, err = d.Section("00001_users.sql", testGooseContent, 0)
+	require.ErrorIs(t, err, ErrWrongDirection)
+}
+
+func TestFlywayDialect_Section(t *testing.T) {
+	testDirName := t.TempDir()
+
+	path := filepath.Join(testDirName, "V2__add_users.sql")
+	content := "CREATE TABLE users (id int);\nCREATE FUNCTION f() RETURNS int AS $body$ SELECT 1; $body$ LANGUAGE sql;"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	d := findSQLDialect(filepath.Base(path), content)
+	require.IsType(t, FlywayDialect{}, d)
+
+	up, err := d.Section(path, content, UpDirection)
+	require.NoError(t, err)
+	require.False(t, up.NoTx)
+	require.Equal(t, []string{
+		"CREATE TABLE users (id int)",
+		"CREATE FUNCTION f() RETURNS int AS $body$ SELECT 1; $body$ LANGUAGE sql",
+	}, up.Statements)
+
+	_, err = d.Section(path, content, DownDirection)
+	require.ErrorIs(t, err, ErrWrongFileFormat)
+
+	undoPath := migfile.FlywayUndoPath(path)
+	require.NoError(t, os.WriteFile(undoPath, []byte("DROP TABLE users;"), 0o600))
+	require.NoError(t, os.WriteFile(undoPath+".conf", []byte("executeInTransaction = false\n"), 0o600))
+
+	down, err := d.Section(path, content, DownDirection)
+	require.NoError(t, err)
+	require.True(t, down.NoTx)
+	require.Equal(t, []string{"DROP TABLE users"}, down.Statements)
+
+	for _, name := range []string{"V1.1__index.sql", "V2_1__data.sql"} {
+		require.IsType(t, FlywayDialect{}, findSQLDialect(name, "CREATE INDEX i ON users (id);"))
+	}
+}
+
+func TestNativeDialect_Section(t *testing.T) {
+	content := migfile.SQLNoTransactionID + "\n" + migfile.SQLUpPartID + `
+CREATE TABLE t (id int);
+` + migfile.SQLStatementBeginID + `
+DO $$ BEGIN PERFORM 1; END $$;
+` + migfile.SQLStatementEndID + `
+` + migfile.SQLDownPartID + `
+DROP TABLE t;
+`
+
+	d := findSQLDialect("00001_t.sql", content)
+	require.IsType(t, NativeDialect{}, d)
+
+	up, err := d.Section("00001_t.sql", content, UpDirection)
+	require.NoError(t, err)
+	require.True(t, up.NoTx)
+	require.Equal(t, []string{"CREATE TABLE t (id int)", "DO $$ BEGIN PERFORM 1; END $$;"}, up.Statements)
+}
+
+func TestNativeDialect_SectionComments(t *testing.T) {
+	fn := `CREATE FUNCTION f() RETURNS int AS $$
+-- комментарий внутри тела
+SELECT 1;
+$$ LANGUAGE sql`
+	content := migfile.SQLUpPartID + `
+-- комментарий; вне запроса
+` + fn + `;
+CREATE INDEX i
+ON t (id);
+` + migfile.SQLDownPartID + `
+DROP FUNCTION f;
+`
+
+	up, err := NativeDialect{}.Section("00001_f.sql", content, UpDirection)
+	require.NoError(t, err)
+	require.Equal(t, []string{fn, "CREATE INDEX i\nON t (id)"}, up.Statements)
+}
+
+func TestSplitSQL(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{
+			name: "comments",
+			text: "SELECT 1; -- a;b\n/* c; */ SELECT 2;",
+			want: []string{"SELECT 1", "SELECT 2"},
+		},
+		{
+			name: "quoted identifiers",
+			text: `SELECT "a;b" FROM t;;;`,
+			want: []string{`SELECT "a;b" FROM t`},
+		},
+		{
+			name: "positional parameter",
+			text: "PREPARE p AS SELECT $1; EXECUTE p(1);",
+			want: []string{"PREPARE p AS SELECT $1", "EXECUTE p(1)"},
+		},
+		{
+			name: "empty",
+			text: ";;",
+			want: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, splitSQL(tt.text))
+		})
+	}
+}
+
+func TestJoinStatements_RoundTrip(t *testing.T) {
+	desired := migdb.Catalog{
+		{Kind: migdb.KindTable, Schema: "public", Name: "users", Table: "users", Definition: "CREATE TABLE public.users"},
+		{Kind: migdb.KindColumn, Schema: "public", Name: "id", Table: "users", Position: 1, Definition: "id integer NOT NULL"},
+		{Kind: migdb.KindColumn, Schema: "public", Name: "name", Table: "users", Position: 2, Definition: "name text DEFAULT 'a;b'::text"},
+		{Kind: migdb.KindView, Schema: "public", Name: "user_names",
+			Definition: "CREATE VIEW public.user_names AS SELECT users.name\n   FROM public.users"},
+		{Kind: migdb.KindFunction, Schema: "public", Name: "one()",
+			Definition: "CREATE OR REPLACE FUNCTION public.one()\n RETURNS integer\n LANGUAGE plpgsql\n" +
+				"AS $function$\nBEGIN\n  RETURN 1;\nEND;\n$function$"},
+	}
+
+	up := migdb.DiffScript(nil, desired)
+	down := migdb.DiffScript(desired, nil)
+	require.Len(t, up, 3)
+
+	for _, layout := range []string{migfile.LayoutSingle, migfile.LayoutSplit} {
+		t.Run(layout, func(t *testing.T) {
+			dir := t.TempDir()
+			tmpl := migfile.NewTemplate(logger.New(logger.LevelDebug), dir)
+			require.NoError(t, tmpl.SetLayout(layout))
+
+			fname, err := tmpl.CreateSQL("diff", JoinStatements(up), JoinStatements(down))
+			require.NoError(t, err)
+
+			path := filepath.Join(dir, fname)
+			content, err := os.ReadFile(path)
+			require.NoError(t, err)
+
+			got, err := ParseSQL(path, string(content), UpDirection)
+			require.NoError(t, err)
+			require.Equal(t, up, got.Statements)
+
+			got, err = ParseSQL(path, string(content), DownDirection)
+			require.NoError(t, err)
+			require.Equal(t, down, got.Statements)
+		})
+	}
+}
@@ -0,0 +1,364 @@
+package executer
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	migfile "github.com/dimonk33/sql-migrator/internal/file"
+)
+
+const (
+	// Длина ключа кэша в имени собранной миграции.
+	cacheKeyLen = 16
+
+	// Подкаталог кэша для временных пакетов сборки.
+	buildDir = "build"
+)
+
+// goToolchain go, которым собираются миграции.
+type goToolchain struct {
+	// Путь к go или имя в PATH.
+	Bin string
+	// GOROOT для go, пустой - значение go по умолчанию.
+	Root string
+}
+
+func defaultToolchain() goToolchain {
+	return goToolchain{Bin: "go"}
+}
+
+// command команда go в каталоге dir, переменные env дополняют среду процесса.
+func (tc goToolchain) command(ctx context.Context, dir string, env []string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, tc.Bin, args...)
+	cmd.Dir = dir
+	cmd.Env = os.Environ()
+	if tc.Root != "" {
+		cmd.Env = append(cmd.Env, "GOROOT="+tc.Root)
+	}
+	cmd.Env = append(cmd.Env, env...)
+
+	return cmd
+}
+
+// Build собирает go миграцию из файла или каталога и возвращает путь к исполняемому файлу. Собранные миграции
+// хранятся в каталоге кэша, ключ кэша - хэш исходников миграции, модуля сборки,
+// версии go и версии шаблона запуска, поэтому повторная сборка не выполняется.
+// Сначала миграция ищется в манифесте кэша без вызова go, go нужен только при промахе.
+func (sm *GoMigrate) Build(ctx context.Context, mpath string) (string, error) {
+	mName := strings.TrimSuffix(filepath.Base(mpath), "."+migfile.GoFile)
+
+	srcKey, err := sm.sourceKey(mpath)
+	if err != nil {
+		return "", fmt.Errorf("ключ исходников миграции: %w", err)
+	}
+
+	if binPath, ok := sm.lookupManifest(srcKey); ok {
+		sm.logger.Debug("миграция", mName, "взята из кэша по манифесту:", binPath)
+		return binPath, nil
+	}
+
+	src, err := sm.parseFile(mpath)
+	if err != nil {
+		return "", fmt.Errorf("ошибка парсинга файла: %w", err)
+	}
+
+	isDir := migfile.IsGoDir(mpath)
+
+	// Пакет сборки создается в каталоге кэша: временный каталог системы может быть недоступен для записи
+	if err = os.MkdirAll(filepath.Join(sm.cacheDir, buildDir), 0o750); err != nil {
+		return "", fmt.Errorf("ошибка создания каталога кэша: %w", err)
+	}
+
+	mDirPath, err := os.MkdirTemp(filepath.Join(sm.cacheDir, buildDir), mName)
+	if err != nil {
+		return "", fmt.Errorf("ошибка создания каталога: %w", err)
+	}
+
+	defer func() {
+		sm.logger.Info("удаление каталога миграции:", os.RemoveAll(mDirPath))
+	}()
+
+	if isDir {
+		if err = copyAssets(mpath, mDirPath); err != nil {
+			return "", fmt.Errorf("ошибка копирования файлов миграции: %w", err)
+		}
+	}
+
+	if _, err = sm.genMainFile(mDirPath, src); err != nil {
+		return "", fmt.Errorf("ошибка создания main файла: %w", err)
+	}
+
+	hm, modFlag, err := sm.genModule(ctx, mDirPath, filepath.Dir(mpath))
+	if err != nil {
+		return "", err
+	}
+
+	key, deps, err := sm.cacheKey(ctx, mDirPath, modFlag, hm)
+	if err != nil {
+		return "", fmt.Errorf("ключ кэша миграции: %w", err)
+	}
+
+	binPath := filepath.Join(sm.cacheDir, mName+"-"+key[:cacheKeyLen]+exeSuffix())
+	if _, err = os.Stat(binPath); err == nil {
+		sm.logger.Debug("миграция", mName, "взята из кэша:", binPath)
+		sm.recordManifest(srcKey, binPath, deps)
+		return binPath, nil
+	}
+
+	// Сборка во временный файл и переименование, чтобы в кэш не попал недособранный файл
+	tmpBin, err := os.CreateTemp(sm.cacheDir, mName+"-*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("ошибка создания файла в кэше: %w", err)
+	}
+	tmpPath := tmpBin.Name()
+	if err = tmpBin.Close(); err != nil {
+		return "", err
+	}
+
+	if err = sm.compile(ctx, mDirPath, modFlag, tmpPath, mName); err != nil {
+		_ = os.Remove(tmpPath)
+		return "", fmt.Errorf("сборка миграции: %w", err)
+	}
+
+	if err = os.Rename(tmpPath, binPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return "", fmt.Errorf("ошибка сохранения миграции в кэш: %w", err)
+	}
+
+	sm.recordManifest(srcKey, binPath, deps)
+
+	return binPath, nil
+}
+
+// compile собирает пакет миграции в файл output. Файлы модуля создаются заранее,
+// tidy нужен только для модуля без go.sum проекта и требует доступа к сети.
+func (sm *GoMigrate) compile(ctx context.Context, srcDirPath string, modFlag string, output string, mName string) error {
+	prefix := "[build " + mName + "]"
+
+	env := make([]string, 0, 1)
+	if modFlag != "" {
+		env = append(env, "GOFLAGS="+modFlag)
+	} else {
+		if err := runProcess(ctx, sm.logger, sm.toolchain.command(ctx, srcDirPath, env, "mod", "tidy"), prefix); err != nil {
+			return fmt.Errorf("go mod tidy: %w", err)
+		}
+	}
+
+	return runProcess(ctx, sm.logger, sm.toolchain.command(ctx, srcDirPath, env, "build", "-o", output, "."), prefix)
+}
+
+// cacheKey хэш версии шаблона, версии go, файлов пакета сборки миграции
+// и всех пакетов, от которых миграция зависит, кроме пакетов с версией из кэша модулей.
+// Вместе с ключом возвращаются файлы пакетов без версии, они проверяются по манифесту.
+func (sm *GoMigrate) cacheKey(ctx context.Context, dirPath string, modFlag string, hm *hostModule) (string, []string, error) {
+	goVersion, err := sm.toolchainVersion(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+
+	h := sha256.New()
+	h.Write([]byte("template " + migfile.GoTemplateVersion + "\n"))
+	h.Write([]byte("go " + goVersion + "\n"))
+	h.Write([]byte("os " + runtime.GOOS + "/" + runtime.GOARCH + "\n"))
+
+	// Каталоги vendor проекта подключены ссылками, их состав задает vendor/modules.txt
+	err = filepath.WalkDir(dirPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(dirPath, path)
+		if err != nil {
+			return err
+		}
+		h.Write([]byte(filepath.ToSlash(rel) + "\n"))
+
+		if d.Type()&fs.ModeSymlink != 0 {
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			h.Write([]byte(target))
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		h.Write(content)
+
+		return nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Без модуля проекта зависимости миграции загружаются из сети и задаются версиями в go.mod
+	if hm == nil {
+		return hex.EncodeToString(h.Sum(nil)), nil, nil
+	}
+
+	deps, err := sm.listDeps(ctx, dirPath, modFlag)
+	if err != nil {
+		return "", nil, fmt.Errorf("зависимости миграции: %w", err)
+	}
+
+	files := make([]string, 0)
+
+	for _, p := range deps {
+		h.Write([]byte("package " + p.ImportPath + "\n"))
+		if p.Module != nil && p.Module.Version != "" && (p.Module.Replace == nil || p.Module.Replace.Version != "") {
+			// Модуль с версией неизменен, его сумма уже есть в go.sum
+			h.Write([]byte(p.Module.Path + "@" + p.Module.Version + "\n"))
+			continue
+		}
+
+		for _, name := range p.files() {
+			path := filepath.Join(p.Dir, name)
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return "", nil, err
+			}
+			files = append(files, path)
+			h.Write([]byte(name + "\n"))
+			h.Write(content)
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), files, nil
+}
+
+// goPackage пакет из вывода go list -json.
+type goPackage struct {
+	ImportPath string
+	Dir        string
+	Standard   bool
+	Module     *goPackageModule
+	GoFiles    []string
+	CgoFiles   []string
+	CFiles     []string
+	HFiles     []string
+	SFiles     []string
+	EmbedFiles []string
+}
+
+type goPackageModule struct {
+	Path    string
+	Version string
+	Replace *goPackageModule
+}
+
+// files файлы пакета, которые влияют на сборку.
+func (p goPackage) files() []string {
+	out := make([]string, 0, len(p.GoFiles)+len(p.CgoFiles)+len(p.EmbedFiles))
+	for _, list := range [][]string{p.GoFiles, p.CgoFiles, p.CFiles, p.HFiles, p.SFiles, p.EmbedFiles} {
+		out = append(out, list...)
+	}
+
+	return out
+}
+
+// listDeps пакеты, от которых зависит пакет сборки миграции, кроме стандартной библиотеки и самого пакета.
+// В их число входят пакеты проекта, импортируемые косвенно, и модули, подмененные каталогом через replace.
+func (sm *GoMigrate) listDeps(ctx context.Context, dirPath string, modFlag string) ([]goPackage, error) {
+	env := make([]string, 0, 1)
+	if modFlag != "" {
+		env = append(env, "GOFLAGS="+modFlag)
+	}
+
+	stderr := &bytes.Buffer{}
+	cmd := sm.toolchain.command(ctx, dirPath, env, "list", "-deps",
+		"-json=ImportPath,Dir,Standard,Module,GoFiles,CgoFiles,CFiles,HFiles,SFiles,EmbedFiles", ".")
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	deps := make([]goPackage, 0)
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for dec.More() {
+		var p goPackage
+		if err = dec.Decode(&p); err != nil {
+			return nil, fmt.Errorf("разбор вывода go list: %w", err)
+		}
+
+		if p.Standard || p.ImportPath == migrateModulePath {
+			continue
+		}
+		deps = append(deps, p)
+	}
+
+	sort.Slice(deps, func(i, j int) bool {
+		return deps[i].ImportPath < deps[j].ImportPath
+	})
+
+	return deps, nil
+}
+
+// toolchainVersion версия go, которой собираются миграции.
+func (sm *GoMigrate) toolchainVersion(ctx context.Context) (string, error) {
+	if sm.goVersion != "" {
+		return sm.goVersion, nil
+	}
+
+	out, err := sm.toolchain.command(ctx, "", nil, "env", "GOVERSION").Output()
+	if err != nil {
+		return "", fmt.Errorf("определение версии go: %w", err)
+	}
+	sm.goVersion = strings.TrimSpace(string(out))
+
+	return sm.goVersion, nil
+}
+
+func exeSuffix() string {
+	if runtime.GOOS == "windows" {
+		return ".exe"
+	}
+
+	return ""
+}
+
+// copyAssets копирует в пакет сборки файлы каталога миграции, кроме go файлов пакета:
+// они нужны миграции для go:embed.
+func copyAssets(srcDir string, dstDir string) error {
+	return filepath.WalkDir(srcDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(srcDir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		dst := filepath.Join(dstDir, rel)
+
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(dst, 0o750)
+		case filepath.Dir(rel) == "." && filepath.Ext(rel) == "."+migfile.GoFile:
+			return nil
+		case !d.Type().IsRegular():
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		return os.WriteFile(dst, content, 0o600)
+	})
+}
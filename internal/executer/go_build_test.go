@@ -0,0 +1,180 @@
+package executer
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dimonk33/sql-migrator/internal/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyAssets(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(src, "up.go"), []byte("package m"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "README"), []byte("readme"), 0o600))
+	require.NoError(t, os.Mkdir(filepath.Join(src, "testdata"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "testdata", "users.csv"), []byte("1,a"), 0o600))
+
+	require.NoError(t, copyAssets(src, dst))
+
+	_, err := os.Stat(filepath.Join(dst, "up.go"))
+	require.True(t, os.IsNotExist(err))
+
+	content, err := os.ReadFile(filepath.Join(dst, "testdata", "users.csv"))
+	require.NoError(t, err)
+	require.Equal(t, "1,a", string(content))
+}
+
+func TestGoMigrate_BuildCache(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go не найден")
+	}
+
+	root := t.TempDir()
+	writeFile := func(rel string, content string) {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o750))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+
+	// Драйвер запуска миграции берется из кэша модулей по сумме из go.sum мигратора
+	sum, err := os.ReadFile("../../go.sum")
+	require.NoError(t, err)
+	driverSum := make([]string, 0, 2)
+	for _, line := range strings.Split(string(sum), "\n") {
+		if strings.HasPrefix(line, driverModulePath+" "+driverModuleVersion) {
+			driverSum = append(driverSum, line)
+		}
+	}
+
+	writeFile("go.mod", "module example.com/app\n\ngo 1.20\n\nrequire example.com/lib v0.0.0\n\nreplace example.com/lib => ./lib\n")
+	writeFile("lib/go.mod", "module example.com/lib\n\ngo 1.20\n")
+	writeFile("lib/lib.go", "package lib\n\nconst Prefix = \"x\"\n")
+	writeFile("go.sum", strings.Join(driverSum, "\n")+"\n")
+	writeFile("internal/model/model.go", "package model\n\nconst Name = \"a\"\n")
+	writeFile("pkg/seed/seed.go", `package seed
+
+import (
+	"example.com/app/internal/model"
+	"example.com/lib"
+)
+
+func Name() string { return lib.Prefix + model.Name }
+`)
+	writeFile("migrations/00001_seed.go", `package migrations
+
+import (
+	"database/sql"
+
+	"example.com/app/pkg/seed"
+)
+
+func up(tx *sql.Tx) error {
+	_ = seed.Name()
+	return nil
+}
+
+func down(tx *sql.Tx) error {
+	return nil
+}
+`)
+
+	gm := NewGoMigrate(nil, logger.New(logger.LevelDebug))
+	gm.SetCacheDir(t.TempDir())
+	mpath := filepath.Join(root, "migrations", "00001_seed.go")
+
+	first, err := gm.Build(context.Background(), mpath)
+	require.NoError(t, err)
+	info, err := os.Stat(first)
+	require.NoError(t, err)
+
+	// Повторная сборка берется из кэша
+	second, err := gm.Build(context.Background(), mpath)
+	require.NoError(t, err)
+	require.Equal(t, first, second)
+	again, err := os.Stat(second)
+	require.NoError(t, err)
+	require.Equal(t, info.ModTime(), again.ModTime())
+
+	// Собранная миграция находится по манифесту без go
+	path := os.Getenv("PATH")
+	t.Setenv("PATH", "")
+	cached, err := gm.Build(context.Background(), mpath)
+	require.NoError(t, err)
+	require.Equal(t, first, cached)
+	require.NoError(t, os.Setenv("PATH", path))
+
+	// Изменение пакета проекта, импортируемого косвенно, требует новой сборки
+	writeFile("internal/model/model.go", "package model\n\nconst Name = \"b\"\n")
+	third, err := gm.Build(context.Background(), mpath)
+	require.NoError(t, err)
+	require.NotEqual(t, first, third)
+
+	// Изменение модуля, подмененного каталогом через replace, требует новой сборки
+	writeFile("lib/lib.go", "package lib\n\nconst Prefix = \"y\"\n")
+	fourth, err := gm.Build(context.Background(), mpath)
+	require.NoError(t, err)
+	require.NotEqual(t, third, fourth)
+}
+
+func TestGoMigrate_Manifest(t *testing.T) {
+	root := t.TempDir()
+	mpath := filepath.Join(root, "migrations", "00001_seed.go")
+	dep := filepath.Join(root, "internal", "model", "model.go")
+	require.NoError(t, os.MkdirAll(filepath.Dir(mpath), 0o750))
+	require.NoError(t, os.MkdirAll(filepath.Dir(dep), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/app\n"), 0o600))
+	require.NoError(t, os.WriteFile(mpath, []byte("package migrations\n"), 0o600))
+	require.NoError(t, os.WriteFile(dep, []byte("package model\n"), 0o600))
+
+	// go не вызывается: путь к go не существует
+	gm := NewGoMigrate(nil, logger.New(logger.LevelDebug))
+	gm.SetCacheDir(t.TempDir())
+	gm.SetToolchain(filepath.Join(root, "no-go"), "")
+
+	key, err := gm.sourceKey(mpath)
+	require.NoError(t, err)
+	_, ok := gm.lookupManifest(key)
+	require.False(t, ok)
+
+	binPath := filepath.Join(gm.cacheDir, "00001_seed-0123456789abcdef"+exeSuffix())
+	require.NoError(t, os.WriteFile(binPath, []byte("bin"), 0o600))
+	gm.recordManifest(key, binPath, []string{dep})
+
+	got, ok := gm.lookupManifest(key)
+	require.True(t, ok)
+	require.Equal(t, binPath, got)
+
+	got, err = gm.Build(context.Background(), mpath)
+	require.NoError(t, err)
+	require.Equal(t, binPath, got)
+
+	// Новый файл в пакете проекта
+	extra := filepath.Join(filepath.Dir(dep), "extra.go")
+	require.NoError(t, os.WriteFile(extra, []byte("package model\n"), 0o600))
+	_, ok = gm.lookupManifest(key)
+	require.False(t, ok)
+	require.NoError(t, os.Remove(extra))
+
+	// Измененный файл пакета проекта
+	require.NoError(t, os.WriteFile(dep, []byte("package model\n\nconst A = 1\n"), 0o600))
+	_, ok = gm.lookupManifest(key)
+	require.False(t, ok)
+
+	// Измененные go.mod и файл миграции меняют ключ исходников
+	require.NoError(t, os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/app\n\ngo 1.20\n"), 0o600))
+	changed, err := gm.sourceKey(mpath)
+	require.NoError(t, err)
+	require.NotEqual(t, key, changed)
+
+	require.NoError(t, os.WriteFile(mpath, []byte("package migrations\n\n"), 0o600))
+	again, err := gm.sourceKey(mpath)
+	require.NoError(t, err)
+	require.NotEqual(t, changed, again)
+}
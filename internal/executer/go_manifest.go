@@ -0,0 +1,235 @@
+package executer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	migfile "github.com/dimonk33/sql-migrator/internal/file"
+)
+
+// Манифест кэша: ключ исходников миграции и собранная по ним миграция.
+// По манифесту собранная миграция находится без вызова go, поэтому заранее собранные
+// миграции запускаются и там, где go не установлен.
+const manifestFile = "manifest.json"
+
+// manifestEntry собранная миграция и хэши файлов пакетов проекта без версии,
+// от которых она зависит, на момент сборки. Хэш состава каталога пакета
+// отслеживает добавление и удаление файлов.
+type manifestEntry struct {
+	Binary string            `json:"binary"`
+	Files  map[string]string `json:"files,omitempty"`
+	Dirs   map[string]string `json:"dirs,omitempty"`
+}
+
+type buildManifest map[string]manifestEntry
+
+// sourceKey хэш всего, что известно без go: версии шаблона, платформы, настройки go,
+// файлов миграции и файлов модуля проекта. Версия go в ключ не входит: после обновления go
+// в том же месте используется миграция, собранная прежней версией.
+func (sm *GoMigrate) sourceKey(mpath string) (string, error) {
+	mpath, err := filepath.Abs(mpath)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	h.Write([]byte("template " + migfile.GoTemplateVersion + "\n"))
+	h.Write([]byte("os " + runtime.GOOS + "/" + runtime.GOARCH + "\n"))
+	h.Write([]byte("toolchain " + sm.toolchain.Bin + " " + sm.toolchain.Root + "\n"))
+	h.Write([]byte("migration " + mpath + "\n"))
+
+	err = filepath.WalkDir(mpath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !d.Type().IsRegular() {
+			return err
+		}
+
+		rel, err := filepath.Rel(mpath, path)
+		if err != nil {
+			return err
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		h.Write([]byte(filepath.ToSlash(rel) + "\n"))
+		h.Write(content)
+
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	root, err := findModuleRoot(filepath.Dir(mpath))
+	if err != nil {
+		// Без модуля проекта зависимости задаются только файлами миграции
+		if errors.Is(err, errNoHostModule) {
+			return hex.EncodeToString(h.Sum(nil)), nil
+		}
+		return "", err
+	}
+
+	for _, name := range []string{goModFile, goSumFile, filepath.Join(vendorDir, vendorModules)} {
+		content, err := os.ReadFile(filepath.Join(root, name))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return "", err
+		}
+		h.Write([]byte(filepath.ToSlash(name) + "\n"))
+		h.Write(content)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// lookupManifest собранная миграция по ключу исходников, если она есть в кэше
+// и файлы пакетов проекта, от которых она зависит, не изменились со времени сборки.
+func (sm *GoMigrate) lookupManifest(key string) (string, bool) {
+	manifest, err := sm.readManifest()
+	if err != nil {
+		sm.logger.Warning("ошибка чтения манифеста кэша:", err)
+		return "", false
+	}
+
+	entry, ok := manifest[key]
+	if !ok {
+		return "", false
+	}
+
+	binPath := filepath.Join(sm.cacheDir, entry.Binary)
+	if _, err = os.Stat(binPath); err != nil {
+		return "", false
+	}
+
+	for path, sum := range entry.Files {
+		if fileSum(path) != sum {
+			return "", false
+		}
+	}
+
+	for dir, sum := range entry.Dirs {
+		if dirSum(dir) != sum {
+			return "", false
+		}
+	}
+
+	return binPath, true
+}
+
+// recordManifest записывает в манифест собранную миграцию и хэши файлов deps.
+// Ошибка записи не мешает запуску миграции: в следующий раз она будет найдена через go.
+func (sm *GoMigrate) recordManifest(key string, binPath string, deps []string) {
+	entry := manifestEntry{
+		Binary: filepath.Base(binPath),
+		Files:  make(map[string]string, len(deps)),
+		Dirs:   make(map[string]string),
+	}
+	for _, path := range deps {
+		entry.Files[path] = fileSum(path)
+		entry.Dirs[filepath.Dir(path)] = dirSum(filepath.Dir(path))
+	}
+
+	if err := sm.writeManifest(key, entry); err != nil {
+		sm.logger.Warning("ошибка записи манифеста кэша:", err)
+	}
+}
+
+func (sm *GoMigrate) readManifest() (buildManifest, error) {
+	content, err := os.ReadFile(filepath.Join(sm.cacheDir, manifestFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return buildManifest{}, nil
+		}
+		return nil, err
+	}
+
+	manifest := buildManifest{}
+	if err = json.Unmarshal(content, &manifest); err != nil {
+		return nil, fmt.Errorf("разбор %s: %w", manifestFile, err)
+	}
+
+	return manifest, nil
+}
+
+// writeManifest добавляет запись в манифест. Манифест записывается во временный файл
+// и переименовывается, чтобы параллельный запуск не прочитал его частично.
+func (sm *GoMigrate) writeManifest(key string, entry manifestEntry) error {
+	manifest, err := sm.readManifest()
+	if err != nil {
+		// Поврежденный манифест собирается заново
+		manifest = buildManifest{}
+	}
+
+	// Записи удаленных из кэша миграций не нужны
+	for k, e := range manifest {
+		if _, err = os.Stat(filepath.Join(sm.cacheDir, e.Binary)); err != nil {
+			delete(manifest, k)
+		}
+	}
+	manifest[key] = entry
+
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(sm.cacheDir, manifestFile+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	if _, err = tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	if err = os.Rename(tmpPath, filepath.Join(sm.cacheDir, manifestFile)); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	return nil
+}
+
+// fileSum хэш содержимого файла, пустая строка - файл недоступен.
+func fileSum(path string) string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(content)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// dirSum хэш имен файлов каталога, пустая строка - каталог недоступен.
+func dirSum(dir string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+
+	h := sha256.New()
+	for _, e := range entries {
+		h.Write([]byte(e.Name() + "\n"))
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -1,139 +1,158 @@
 package executer
 
 import (
-	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"strings"
+	"time"
 
 	migfile "github.com/dimonk33/sql-migrator/internal/file"
 )
 
-const (
-	prefixErrMigrateDelete = "Удаление записи о миграции "
-)
-
 type GoMigrate struct {
-	db     DBGo
-	logger migfile.Logger
+	db        DBGo
+	logger    migfile.Logger
+	cacheDir  string
+	goVersion string
+	logLevel  string
+	vars      map[string]string
+	timeout   time.Duration
+	toolchain goToolchain
 }
 
+// DBGo база для go миграций: транзакцией и записью в истории миграций управляет
+// собранная миграция, поэтому ей передаются только подключение и запрос истории.
 type DBGo interface {
-	GetConnString() string
-	Create(ctx context.Context, name string) error
-	Exec(ctx context.Context, sql string) error
-	SetApplied(ctx context.Context, name string) error
-	Delete(ctx context.Context, name string) error
+	ConnEnv() []string
+	HistorySQL(up bool) string
 }
 
 func NewGoMigrate(db DBGo, l migfile.Logger) *GoMigrate {
 	return &GoMigrate{
-		db:     db,
-		logger: l,
+		db:        db,
+		logger:    l,
+		cacheDir:  DefaultGoCacheDir(),
+		toolchain: defaultToolchain(),
 	}
 }
 
-func (sm *GoMigrate) exec(mFuncName string, mpath string) error {
-	var (
-		mFuncContent string
-		mDirPath     string
-		err          error
-	)
-
-	mFuncContent, err = sm.parseFile(mpath)
+// DefaultGoCacheDir каталог кэша собранных go миграций по умолчанию.
+func DefaultGoCacheDir() string {
+	dir, err := os.UserCacheDir()
 	if err != nil {
-		return fmt.Errorf("ошибка парсинга файла: %w", err)
+		dir = os.TempDir()
 	}
 
-	mDirPath, err = os.MkdirTemp("", strings.TrimRight(filepath.Base(mpath), "."+migfile.GoFile))
-	if err != nil {
-		return fmt.Errorf("ошибка создания каталога: %w", err)
-	}
+	return filepath.Join(dir, "gomigrator")
+}
 
-	_, err = sm.genMainFile(mDirPath, mFuncContent, mFuncName)
-	if err != nil {
-		return fmt.Errorf("ошибка создания main файла: %w", err)
+func (sm *GoMigrate) SetCacheDir(dir string) {
+	sm.cacheDir = dir
+}
+
+// SetToolchain задает go для сборки миграций: путь к go и GOROOT, пустые значения - по умолчанию.
+func (sm *GoMigrate) SetToolchain(goBin string, goRoot string) {
+	sm.toolchain = defaultToolchain()
+	if goBin != "" {
+		sm.toolchain.Bin = goBin
 	}
+	sm.toolchain.Root = goRoot
+	sm.goVersion = ""
+}
 
-	err = sm.execMigration(mDirPath)
+// SetTimeout задает время выполнения одной миграции, 0 - без ограничения.
+func (sm *GoMigrate) SetTimeout(d time.Duration) {
+	sm.timeout = d
+}
 
-	sm.logger.Info("удаление каталога миграции:", os.RemoveAll(mDirPath))
+// SetEnv задает уровень логирования и переменные, доступные миграции через gomigrator.Env.
+func (sm *GoMigrate) SetEnv(logLevel string, vars map[string]string) {
+	sm.logLevel = logLevel
+	sm.vars = vars
+}
 
+func (sm *GoMigrate) exec(ctx context.Context, mFuncName string, mpath string) error {
+	binPath, err := sm.Build(ctx, mpath)
 	if err != nil {
+		return err
+	}
+
+	if err = sm.execMigration(ctx, binPath, mFuncName, filepath.Base(mpath)); err != nil {
 		return fmt.Errorf("запуск миграции: %w", err)
 	}
 
 	return nil
 }
 
+// UpExec применяет миграцию, запись о применении делается в транзакции миграции.
 func (sm *GoMigrate) UpExec(ctx context.Context, mpath string) error {
-	mName := filepath.Base(mpath)
-
-	if err := sm.db.Create(ctx, mName); err != nil {
-		return fmt.Errorf("регистрация миграции: %w", err)
-	}
-
-	if err := sm.exec(migfile.GoUpFuncName, mpath); err != nil {
-		sm.logger.Warning(prefixErrMigrateDelete, sm.db.Delete(ctx, mName))
+	if err := sm.exec(ctx, migfile.GoUpFuncName, mpath); err != nil {
 		return fmt.Errorf("применение миграции: %w", err)
 	}
 
-	if err := sm.db.SetApplied(ctx, mName); err != nil {
-		return fmt.Errorf("закрытие миграции: %w", err)
-	}
-
 	return nil
 }
 
+// DownExec откатывает миграцию, запись о применении удаляется в транзакции миграции.
 func (sm *GoMigrate) DownExec(ctx context.Context, mpath string) error {
-	mName := filepath.Base(mpath)
-
-	if err := sm.exec(migfile.GoDownFuncName, mpath); err != nil {
+	if err := sm.exec(ctx, migfile.GoDownFuncName, mpath); err != nil {
 		return fmt.Errorf("откат миграции: %w", err)
 	}
 
-	if err := sm.db.Delete(ctx, mName); err != nil {
-		return fmt.Errorf("удаление записи о миграции: %w", err)
-	}
-
 	return nil
 }
 
-func (sm *GoMigrate) parseFile(path string) (string, error) {
-	fileContent, err := os.ReadFile(path)
-	if err != nil {
-		return "", fmt.Errorf("ошибка открытия файла: %w", err)
+// parseFile разбирает go миграцию из файла или каталога с go файлами.
+func (sm *GoMigrate) parseFile(path string) (*goSource, error) {
+	if !migfile.IsGoDir(path) {
+		fileContent, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка открытия файла: %w", err)
+		}
+
+		return parseGoSource(path, fileContent)
 	}
 
-	fileStr := string(fileContent)
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения каталога: %w", err)
+	}
 
-	upStartIndex := strings.Index(fileStr, migfile.GoUpPartID)
-	downStartIndex := strings.Index(fileStr, migfile.GoDownPartID)
+	files := make(map[string][]byte)
+	for _, e := range entries {
+		if !migfile.IsGoSource(e) {
+			continue
+		}
+		if e.Name() == migfile.GoMainFile {
+			return nil, fmt.Errorf("%w: имя файла %s занято запуском миграции", ErrWrongFileFormat, e.Name())
+		}
 
-	if upStartIndex == -1 || downStartIndex == -1 {
-		return "", ErrWrongFileFormat
+		content, err := os.ReadFile(filepath.Join(path, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("ошибка открытия файла: %w", err)
+		}
+		files[filepath.Join(path, e.Name())] = content
 	}
 
-	return fileStr, nil
+	return parseGoPackage(files)
 }
 
-func (sm *GoMigrate) genMainFile(
-	dirPath string,
-	migrateContent string,
-	callFuncName string,
-) (string, error) {
+// genMainFile создает файлы миграции и main файл в пакете сборки.
+func (sm *GoMigrate) genMainFile(dirPath string, src *goSource) (string, error) {
 	const prefixErrMsg = "генерация main файла"
 
-	var (
-		mainFilePath string
-		err          error
-	)
+	for _, f := range src.Files {
+		if err := os.WriteFile(filepath.Join(dirPath, f.Name), f.Content, 0o600); err != nil {
+			return "", fmt.Errorf("%s: %w", prefixErrMsg, err)
+		}
+	}
 
 	t := migfile.NewTemplate(sm.logger, dirPath)
-	mainFilePath, err = t.CreateGoMain(migrateContent, callFuncName, sm.db.GetConnString())
+	mainFilePath, err := t.CreateGoMain(src.Call)
 	if err != nil {
 		return "", fmt.Errorf("%s: %w", prefixErrMsg, err)
 	}
@@ -141,30 +160,58 @@ func (sm *GoMigrate) genMainFile(
 	return mainFilePath, nil
 }
 
-func (sm *GoMigrate) execMigration(srcDirPath string) error {
-	t := migfile.NewTemplate(sm.logger, srcDirPath)
-
-	runFilePath, err := t.CreateRunSh()
+// genModule создает модуль миграции на основе модуля проекта, в котором находятся миграции:
+// сборка использует go.sum, кэш модулей и vendor проекта и не требует сети.
+// Без модуля проекта зависимости загружаются через go mod tidy.
+// Возвращает модуль проекта и режим работы с зависимостями, пустой режим означает сборку с tidy.
+func (sm *GoMigrate) genModule(ctx context.Context, dirPath string, migrateDir string) (*hostModule, string, error) {
+	hm, err := findHostModule(ctx, migrateDir, sm.toolchain)
 	if err != nil {
-		return fmt.Errorf("генерация run файла: %w", err)
+		if !errors.Is(err, errNoHostModule) {
+			return nil, "", fmt.Errorf("модуль проекта: %w", err)
+		}
+
+		sm.logger.Warning("go.mod проекта не найден, зависимости миграции будут загружены из сети")
+		goMod := "module " + migrateModulePath + "\n"
+		if err = os.WriteFile(filepath.Join(dirPath, goModFile), []byte(goMod), 0o600); err != nil {
+			return nil, "", fmt.Errorf("ошибка создания %s: %w", goModFile, err)
+		}
+
+		return nil, "", nil
 	}
 
-	cmdOutput := &bytes.Buffer{}
-	cmd := exec.Command(runFilePath)
-	cmd.Stdout = cmdOutput
+	if err = hm.Write(dirPath); err != nil {
+		return nil, "", fmt.Errorf("модуль миграции: %w", err)
+	}
 
-	err = cmd.Run()
-	if err != nil {
-		_, errO := os.Stderr.WriteString(err.Error())
-		if errO != nil {
-			sm.logger.Warning(errO)
-		}
-		sm.logger.Error(err)
+	return hm, hm.ModFlag(), nil
+}
 
-		return fmt.Errorf("сборка миграции: %w", err)
+// execMigration запускает собранную миграцию, параметры подключения передаются через среду.
+// Вывод миграции передается в логгер, по истечении времени миграция завершается вместе с дочерними процессами.
+func (sm *GoMigrate) execMigration(ctx context.Context, binPath string, mFuncName string, mName string) error {
+	if sm.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, sm.timeout)
+		defer cancel()
 	}
 
-	fmt.Print(cmdOutput.String())
+	cmd := exec.CommandContext(ctx, binPath, mFuncName, mName)
+	cmd.Env = append(os.Environ(), sm.db.ConnEnv()...)
+	cmd.Env = append(cmd.Env, migfile.GoHistorySQLEnv+"="+sm.db.HistorySQL(mFuncName == migfile.GoUpFuncName))
+	cmd.Env = append(cmd.Env, migfile.GoLogLevelEnv+"="+sm.logLevel)
+
+	if len(sm.vars) > 0 {
+		vars, err := json.Marshal(sm.vars)
+		if err != nil {
+			return fmt.Errorf("переменные миграции: %w", err)
+		}
+		cmd.Env = append(cmd.Env, migfile.GoVarsEnv+"="+string(vars))
+	}
+
+	if err := runProcess(ctx, sm.logger, cmd, "["+mName+"]"); err != nil {
+		return fmt.Errorf("выполнение миграции: %w", err)
+	}
 
 	return nil
 }
@@ -3,6 +3,7 @@ package executer
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	migfile "github.com/dimonk33/sql-migrator/internal/file"
@@ -55,7 +56,7 @@ func TestGoMigrate_parseFile(t *testing.T) {
 			args: args{
 				path: filepath.Join(absTestDataPath, testGoodGoFile),
 			},
-			want:    string(testGoodData),
+			want:    strings.Replace(string(testGoodData), "package testdata", "package main", 1),
 			wantErr: false,
 		},
 		{
@@ -75,15 +76,15 @@ func TestGoMigrate_parseFile(t *testing.T) {
 				logger: tt.fields.logger,
 			}
 
-			var got string
+			var got *goSource
 
 			got, err = sm.parseFile(tt.args.path)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("parseFile() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if got != tt.want {
-				t.Errorf("parseFile() got = %v, want %v", got, tt.want)
+			if got != nil && string(got.Files[0].Content) != tt.want {
+				t.Errorf("parseFile() got = %s, want %v", got.Files[0].Content, tt.want)
 			}
 		})
 	}
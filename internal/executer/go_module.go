@@ -0,0 +1,395 @@
+package executer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	goModFile     = "go.mod"
+	goSumFile     = "go.sum"
+	vendorDir     = "vendor"
+	vendorModules = "modules.txt"
+
+	// Имя сгенерированного модуля миграции.
+	migrateModulePath = "gomigrator/migration"
+
+	// Версия-заглушка для модуля проекта, подменяемого через replace.
+	hostModuleVersion = "v0.0.0-00010101000000-000000000000"
+
+	// Драйвер базы, который импортирует запуск миграции, и его версия из go.mod мигратора.
+	// Версия используется, если проект сам не зависит от драйвера.
+	driverModulePath    = "github.com/lib/pq"
+	driverModuleVersion = "v1.2.0"
+)
+
+// hostModule модуль проекта, в котором находятся миграции.
+type hostModule struct {
+	Root    string
+	Vendor  bool
+	modInfo modInfo
+}
+
+// modInfo вывод go mod edit -json.
+type modInfo struct {
+	Module  modVersion
+	Go      string
+	Require []modRequire
+	Replace []modReplace
+}
+
+type modVersion struct {
+	Path    string
+	Version string
+}
+
+type modRequire struct {
+	Path     string
+	Version  string
+	Indirect bool
+}
+
+type modReplace struct {
+	Old modVersion
+	New modVersion
+}
+
+var (
+	errNoHostModule   = errors.New("go.mod проекта не найден")
+	errNoDriverModule = errors.New("зависимость запуска миграции недоступна без сети")
+)
+
+// findModuleRoot ищет каталог с go.mod в каталоге dir и выше.
+func findModuleRoot(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		if _, err = os.Stat(filepath.Join(dir, goModFile)); err == nil {
+			return dir, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", errNoHostModule
+		}
+		dir = parent
+	}
+}
+
+// findHostModule ищет go.mod в каталоге миграции и выше.
+func findHostModule(ctx context.Context, dir string, tc goToolchain) (*hostModule, error) {
+	dir, err := findModuleRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// go mod edit читает только go.mod и не обращается к сети
+	out := &bytes.Buffer{}
+	cmd := tc.command(ctx, dir, nil, "mod", "edit", "-json")
+	cmd.Stdout = out
+	if err = cmd.Run(); err != nil {
+		return nil, fmt.Errorf("чтение %s: %w", filepath.Join(dir, goModFile), err)
+	}
+
+	hm := &hostModule{Root: dir}
+	if err = json.Unmarshal(out.Bytes(), &hm.modInfo); err != nil {
+		return nil, fmt.Errorf("разбор %s: %w", filepath.Join(dir, goModFile), err)
+	}
+
+	if _, err = os.Stat(filepath.Join(dir, vendorDir, vendorModules)); err == nil {
+		hm.Vendor = true
+	}
+
+	return hm, nil
+}
+
+// ModFlag режим работы с зависимостями при сборке миграции.
+func (hm *hostModule) ModFlag() string {
+	if hm.Vendor {
+		return "-mod=vendor"
+	}
+
+	return "-mod=mod"
+}
+
+// GoMod go.mod модуля миграции: зависимости проекта и replace на каталог проекта,
+// чтобы миграция могла импортировать пакеты проекта.
+func (hm *hostModule) GoMod() string {
+	builder := strings.Builder{}
+	builder.WriteString("module " + migrateModulePath + "\n\n")
+	if hm.modInfo.Go != "" {
+		builder.WriteString("go " + hm.modInfo.Go + "\n\n")
+	}
+
+	builder.WriteString("require (\n")
+	builder.WriteString("\t" + hm.modInfo.Module.Path + " " + hostModuleVersion + "\n")
+	if version, ok := hm.driverVersion(); !ok {
+		builder.WriteString("\t" + driverModulePath + " " + version + "\n")
+	}
+	for _, r := range hm.modInfo.Require {
+		builder.WriteString("\t" + r.Path + " " + r.Version)
+		if r.Indirect {
+			builder.WriteString(" // indirect")
+		}
+		builder.WriteString("\n")
+	}
+	builder.WriteString(")\n\n")
+
+	builder.WriteString("replace " + hm.modInfo.Module.Path + " => " + hm.Root + "\n")
+	for _, r := range hm.modInfo.Replace {
+		old := r.Old.Path
+		if r.Old.Version != "" {
+			old += " " + r.Old.Version
+		}
+
+		newPath := r.New.Path
+		if r.New.Version != "" {
+			newPath += " " + r.New.Version
+		} else if !filepath.IsAbs(newPath) {
+			// Замена на каталог указывается относительно проекта
+			newPath = filepath.Join(hm.Root, newPath)
+		}
+
+		builder.WriteString("replace " + old + " => " + newPath + "\n")
+	}
+
+	return builder.String()
+}
+
+// driverVersion версия драйвера для модуля миграции: версия из зависимостей проекта
+// или версия мигратора, если проект от драйвера не зависит.
+func (hm *hostModule) driverVersion() (string, bool) {
+	for _, r := range hm.modInfo.Require {
+		if r.Path == driverModulePath {
+			return r.Version, true
+		}
+	}
+
+	return driverModuleVersion, false
+}
+
+// checkDriver проверяет, что драйвер можно собрать без сети: он должен быть в vendor проекта
+// или его контрольная сумма - в go.sum проекта. Иначе сборка завершится непонятной ошибкой компилятора.
+func (hm *hostModule) checkDriver() error {
+	version, _ := hm.driverVersion()
+
+	if hm.Vendor {
+		modules, err := os.ReadFile(filepath.Join(hm.Root, vendorDir, vendorModules))
+		if err != nil {
+			return err
+		}
+
+		for _, line := range strings.Split(string(modules), "\n") {
+			if strings.TrimSpace(line) == driverModulePath {
+				return nil
+			}
+		}
+
+		return fmt.Errorf("%w: в vendor проекта нет %s, добавьте зависимость: go get %s@%s && go mod vendor",
+			errNoDriverModule, driverModulePath, driverModulePath, version)
+	}
+
+	sum, err := os.ReadFile(filepath.Join(hm.Root, goSumFile))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("ошибка чтения %s: %w", goSumFile, err)
+	}
+
+	if !strings.Contains("\n"+string(sum), "\n"+driverModulePath+" "+version+" h1:") {
+		return fmt.Errorf("%w: в %s проекта нет %s %s, добавьте зависимость: go get %s@%s",
+			errNoDriverModule, goSumFile, driverModulePath, version, driverModulePath, version)
+	}
+
+	return nil
+}
+
+// Write создает в каталоге dir файлы модуля миграции: go.mod, go.sum проекта
+// и vendor каталог, ссылающийся на vendor проекта.
+func (hm *hostModule) Write(dir string) error {
+	if err := hm.checkDriver(); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, goModFile), []byte(hm.GoMod()), 0o600); err != nil {
+		return fmt.Errorf("ошибка создания %s: %w", goModFile, err)
+	}
+
+	sum, err := os.ReadFile(filepath.Join(hm.Root, goSumFile))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("ошибка чтения %s: %w", goSumFile, err)
+	}
+
+	if err = os.WriteFile(filepath.Join(dir, goSumFile), sum, 0o600); err != nil {
+		return fmt.Errorf("ошибка создания %s: %w", goSumFile, err)
+	}
+
+	if hm.Vendor {
+		if err = hm.writeVendor(filepath.Join(dir, vendorDir)); err != nil {
+			return fmt.Errorf("ошибка создания vendor каталога: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// writeVendor повторяет vendor проекта ссылками на каталоги модулей и добавляет в него
+// сам проект, подключенный через replace.
+func (hm *hostModule) writeVendor(dir string) error {
+	hostVendor := filepath.Join(hm.Root, vendorDir)
+
+	modules, err := os.ReadFile(filepath.Join(hostVendor, vendorModules))
+	if err != nil {
+		return err
+	}
+
+	builder := strings.Builder{}
+	linked := make([]string, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(modules))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "# ") {
+			builder.WriteString(line + "\n")
+			continue
+		}
+
+		builder.WriteString(hm.absReplace(line) + "\n")
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		path := fields[1]
+		if err = linkVendored(filepath.Join(hostVendor, path), filepath.Join(dir, path), linked); err != nil {
+			return err
+		}
+		linked = append(linked, path)
+	}
+
+	if err = scanner.Err(); err != nil {
+		return err
+	}
+
+	if err = linkVendored(hm.Root, filepath.Join(dir, hm.modInfo.Module.Path), nil); err != nil {
+		return err
+	}
+
+	packages, err := hm.packages()
+	if err != nil {
+		return err
+	}
+
+	builder.WriteString("# " + hm.modInfo.Module.Path + " " + hostModuleVersion + " => " + hm.Root + "\n")
+	builder.WriteString("## explicit")
+	if hm.modInfo.Go != "" {
+		builder.WriteString("; go " + hm.modInfo.Go)
+	}
+	builder.WriteString("\n")
+	for _, p := range packages {
+		builder.WriteString(p + "\n")
+	}
+	builder.WriteString("# " + hm.modInfo.Module.Path + " => " + hm.Root + "\n")
+
+	return os.WriteFile(filepath.Join(dir, vendorModules), []byte(builder.String()), 0o600)
+}
+
+// Замена на каталог в modules.txt должна совпадать с go.mod модуля миграции,
+// где относительные пути заменены на абсолютные.
+func (hm *hostModule) absReplace(line string) string {
+	i := strings.Index(line, " => ")
+	if i == -1 {
+		return line
+	}
+
+	newPath := line[i+len(" => "):]
+	if strings.HasPrefix(newPath, "./") || strings.HasPrefix(newPath, "../") {
+		return line[:i] + " => " + filepath.Join(hm.Root, newPath)
+	}
+
+	return line
+}
+
+// Ссылка на каталог модуля, вложенные модули уже доступны через ссылку на родителя.
+func linkVendored(src string, dst string, linked []string) error {
+	for _, l := range linked {
+		if strings.HasPrefix(filepath.ToSlash(dst), filepath.ToSlash(l)+"/") {
+			return nil
+		}
+	}
+
+	if _, err := os.Lstat(dst); err == nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0o750); err != nil {
+		return err
+	}
+
+	return os.Symlink(src, dst)
+}
+
+// packages пакеты проекта для vendor/modules.txt.
+func (hm *hostModule) packages() ([]string, error) {
+	out := make([]string, 0)
+	err := filepath.WalkDir(hm.Root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() {
+			return nil
+		}
+
+		name := d.Name()
+		if path != hm.Root {
+			if name == vendorDir || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+			// Вложенный модуль не входит в проект
+			if _, err = os.Stat(filepath.Join(path, goModFile)); err == nil {
+				return filepath.SkipDir
+			}
+		}
+
+		if hasGoFiles(path) {
+			rel, err := filepath.Rel(hm.Root, path)
+			if err != nil {
+				return err
+			}
+			pkg := hm.modInfo.Module.Path
+			if rel != "." {
+				pkg += "/" + filepath.ToSlash(rel)
+			}
+			out = append(out, pkg)
+		}
+
+		return nil
+	})
+
+	return out, err
+}
+
+func hasGoFiles(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".go") && !strings.HasSuffix(e.Name(), "_test.go") {
+			return true
+		}
+	}
+
+	return false
+}
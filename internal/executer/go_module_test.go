@@ -0,0 +1,96 @@
+package executer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindHostModule(t *testing.T) {
+	hm, err := findHostModule(context.Background(), testDataPath, defaultToolchain())
+	require.NoError(t, err)
+
+	root, err := filepath.Abs("../..")
+	require.NoError(t, err)
+	require.Equal(t, root, hm.Root)
+	require.Equal(t, "github.com/dimonk33/sql-migrator", hm.modInfo.Module.Path)
+	require.Equal(t, "-mod=mod", hm.ModFlag())
+
+	_, err = findHostModule(context.Background(), t.TempDir(), defaultToolchain())
+	require.ErrorIs(t, err, errNoHostModule)
+}
+
+func TestHostModule_Write(t *testing.T) {
+	hm := &hostModule{
+		Root: "/src/app",
+		modInfo: modInfo{
+			Module:  modVersion{Path: "example.com/app"},
+			Go:      "1.20",
+			Require: []modRequire{{Path: "github.com/lib/pq", Version: "v1.2.0"}},
+			Replace: []modReplace{{Old: modVersion{Path: "example.com/lib"}, New: modVersion{Path: "../lib"}}},
+		},
+	}
+
+	require.Equal(t, `module gomigrator/migration
+
+go 1.20
+
+require (
+	example.com/app v0.0.0-00010101000000-000000000000
+	github.com/lib/pq v1.2.0
+)
+
+replace example.com/app => /src/app
+replace example.com/lib => /src/lib
+`, hm.GoMod())
+
+	require.Equal(t, "# example.com/lib v1.0.0 => /src/lib", hm.absReplace("# example.com/lib v1.0.0 => ../lib"))
+	require.Equal(t, "# example.com/x v1.0.0 => example.com/y v1.1.0", hm.absReplace("# example.com/x v1.0.0 => example.com/y v1.1.0"))
+
+	dir := t.TempDir()
+	hm.Root = dir
+	sumLine := "github.com/lib/pq v1.2.0 h1:sum=\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, goSumFile), []byte(sumLine), 0o600))
+
+	out := t.TempDir()
+	require.NoError(t, hm.Write(out))
+
+	sum, err := os.ReadFile(filepath.Join(out, goSumFile))
+	require.NoError(t, err)
+	require.Equal(t, sumLine, string(sum))
+}
+
+func TestHostModule_Driver(t *testing.T) {
+	goMod, err := os.ReadFile("../../go.mod")
+	require.NoError(t, err)
+	require.Contains(t, string(goMod), driverModulePath+" "+driverModuleVersion+"\n")
+
+	dir := t.TempDir()
+	hm := &hostModule{
+		Root:    dir,
+		modInfo: modInfo{Module: modVersion{Path: "example.com/app"}},
+	}
+
+	require.Contains(t, hm.GoMod(), "\t"+driverModulePath+" "+driverModuleVersion+"\n")
+
+	// Проект без драйвера в go.sum
+	require.ErrorIs(t, hm.Write(t.TempDir()), errNoDriverModule)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, goSumFile),
+		[]byte(driverModulePath+" "+driverModuleVersion+" h1:sum=\n"), 0o600))
+	require.NoError(t, hm.Write(t.TempDir()))
+
+	// Vendor проекта без драйвера
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, vendorDir), 0o750))
+	modules := "# example.com/lib v1.0.0\n## explicit\nexample.com/lib\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, vendorDir, vendorModules), []byte(modules), 0o600))
+	hm.Vendor = true
+	require.ErrorIs(t, hm.checkDriver(), errNoDriverModule)
+
+	modules += "# " + driverModulePath + " " + driverModuleVersion + "\n" + driverModulePath + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, vendorDir, vendorModules), []byte(modules), 0o600))
+	require.NoError(t, hm.checkDriver())
+}
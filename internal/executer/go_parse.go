@@ -0,0 +1,218 @@
+package executer
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	migfile "github.com/dimonk33/sql-migrator/internal/file"
+)
+
+// goSource исходники go миграции, переведенные в пакет main, и форма ее функций up и down.
+type goSource struct {
+	Files []goFile
+	Call  migfile.GoCall
+}
+
+// goFile файл пакета go миграции.
+type goFile struct {
+	Name    string
+	Content []byte
+}
+
+// parseGoSource разбирает go миграцию из одного файла.
+func parseGoSource(filename string, content []byte) (*goSource, error) {
+	src, err := parseGoPackage(map[string][]byte{filename: content})
+	if err != nil {
+		return nil, err
+	}
+	src.Files[0].Name = migfile.GoMigrationFile
+
+	return src, nil
+}
+
+// parseGoPackage разбирает файлы go миграции и определяет форму функций up и down:
+// func up(tx *sql.Tx) error или func up(ctx context.Context, tx *sql.Tx, env *gomigrator.Env) error.
+// Файлы должны быть одним пакетом, функции up и down объявляются в любом из них.
+// Ошибки формата содержат позицию в файле миграции.
+func parseGoPackage(files map[string][]byte) (*goSource, error) {
+	fset := token.NewFileSet()
+	src := &goSource{}
+	found := make(map[string]bool, 2)
+	pkgName := ""
+
+	for _, filename := range sortedKeys(files) {
+		f, err := parser.ParseFile(fset, filename, files[filename], parser.ParseComments|parser.SkipObjectResolution)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrWrongFileFormat, err)
+		}
+
+		if pkgName != "" && f.Name.Name != pkgName {
+			return nil, fmt.Errorf("%s: %w: пакет %s, ожидается %s",
+				fset.Position(f.Name.Pos()), ErrWrongFileFormat, f.Name.Name, pkgName)
+		}
+		pkgName = f.Name.Name
+
+		if pos, ok := mainDecl(f); ok {
+			return nil, fmt.Errorf("%s: %w: объявление main занято запуском миграции", fset.Position(pos), ErrWrongFileFormat)
+		}
+
+		imports := make(map[string]string, len(f.Imports))
+		for _, imp := range f.Imports {
+			impPath, err := strconv.Unquote(imp.Path.Value)
+			if err != nil {
+				return nil, err
+			}
+
+			name := path.Base(impPath)
+			if imp.Name != nil {
+				name = imp.Name.Name
+			}
+			imports[impPath] = name
+		}
+
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil || (fn.Name.Name != migfile.GoUpFuncName && fn.Name.Name != migfile.GoDownFuncName) {
+				continue
+			}
+
+			if found[fn.Name.Name] {
+				return nil, fmt.Errorf("%s: %w: функция %s объявлена повторно",
+					fset.Position(fn.Pos()), ErrWrongFileFormat, fn.Name.Name)
+			}
+
+			withEnv, pos, err := funcForm(fn, imports)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w: %w", fset.Position(pos), ErrWrongFileFormat, err)
+			}
+
+			found[fn.Name.Name] = true
+			if fn.Name.Name == migfile.GoUpFuncName {
+				src.Call.UpEnv = withEnv
+			} else {
+				src.Call.DownEnv = withEnv
+			}
+		}
+
+		// Миграция собирается в одном пакете с запуском
+		f.Name.Name = "main"
+
+		buf := &bytes.Buffer{}
+		if err = format.Node(buf, fset, f); err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+		src.Files = append(src.Files, goFile{Name: filepath.Base(filename), Content: buf.Bytes()})
+	}
+
+	for _, name := range []string{migfile.GoUpFuncName, migfile.GoDownFuncName} {
+		if !found[name] {
+			return nil, fmt.Errorf("%s: %w: нет функции %s", strings.Join(sortedKeys(files), ", "), ErrWrongFileFormat, name)
+		}
+	}
+
+	return src, nil
+}
+
+// mainDecl ищет объявление main на уровне пакета.
+func mainDecl(f *ast.File) (token.Pos, bool) {
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil && d.Name.Name == "main" {
+				return d.Pos(), true
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch sp := spec.(type) {
+				case *ast.ValueSpec:
+					for _, n := range sp.Names {
+						if n.Name == "main" {
+							return n.Pos(), true
+						}
+					}
+				case *ast.TypeSpec:
+					if sp.Name.Name == "main" {
+						return sp.Pos(), true
+					}
+				}
+			}
+		}
+	}
+
+	return token.NoPos, false
+}
+
+// funcForm проверяет сигнатуру функции миграции, возвращает true для формы с окружением
+// или позицию ошибки в сигнатуре.
+func funcForm(fn *ast.FuncDecl, imports map[string]string) (bool, token.Pos, error) {
+	if fn.Type.TypeParams != nil {
+		return false, fn.Type.TypeParams.Pos(), fmt.Errorf("функция %s не может быть обобщенной", fn.Name.Name)
+	}
+
+	results := fieldTypes(fn.Type.Results)
+	if len(results) != 1 || results[0] != "error" {
+		pos := fn.Type.Params.End()
+		if fn.Type.Results != nil {
+			pos = fn.Type.Results.Pos()
+		}
+		return false, pos, fmt.Errorf("функция %s должна возвращать только error", fn.Name.Name)
+	}
+
+	params := fieldTypes(fn.Type.Params)
+	txType := "*" + imports["database/sql"] + ".Tx"
+	switch {
+	case len(params) == 1 && params[0] == txType:
+		return false, token.NoPos, nil
+	case len(params) == 3 && imports[migfile.GoEnvPackage] != "" &&
+		params[0] == imports["context"]+".Context" &&
+		params[1] == txType &&
+		params[2] == "*"+imports[migfile.GoEnvPackage]+".Env":
+		return true, token.NoPos, nil
+	}
+
+	return false, fn.Type.Params.Pos(), fmt.Errorf(
+		"функция %s должна иметь сигнатуру (tx *sql.Tx) или (ctx context.Context, tx *sql.Tx, env *gomigrator.Env)",
+		fn.Name.Name,
+	)
+}
+
+// fieldTypes типы параметров списка полей, по одному на каждое имя.
+func fieldTypes(list *ast.FieldList) []string {
+	out := make([]string, 0)
+	if list == nil {
+		return out
+	}
+
+	for _, field := range list.List {
+		typ := types.ExprString(field.Type)
+		n := len(field.Names)
+		if n == 0 {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			out = append(out, typ)
+		}
+	}
+
+	return out
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+
+	return out
+}
@@ -0,0 +1,124 @@
+package executer
+
+import (
+	"strings"
+	"testing"
+
+	migfile "github.com/dimonk33/sql-migrator/internal/file"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGoSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    migfile.GoCall
+		wantErr bool
+	}{
+		{
+			name: "tx form",
+			content: `package migration
+
+import "database/sql"
+
+// func up(ctx context.Context) error
+func up(tx *sql.Tx) error { return nil }
+
+func down(tx *sql.Tx) error { return nil }
+`,
+		},
+		{
+			name: "env form",
+			content: `package migration
+
+import (
+	stdctx "context"
+	"database/sql"
+
+	gm "github.com/dimonk33/sql-migrator/pkg/gomigrator"
+)
+
+func up(ctx stdctx.Context, tx *sql.Tx, env *gm.Env) error { return nil }
+
+func down(tx *sql.Tx) error { return nil }
+`,
+			want: migfile.GoCall{UpEnv: true},
+		},
+		{
+			name: "wrong signature",
+			content: `package migration
+
+import "database/sql"
+
+func up(tx *sql.Tx) (int, error) { return 0, nil }
+
+func down(tx *sql.Tx) error { return nil }
+`,
+			wantErr: true,
+		},
+		{
+			name: "main declared",
+			content: `package migration
+
+import "database/sql"
+
+func up(tx *sql.Tx) error { return nil }
+
+func down(tx *sql.Tx) error { return nil }
+
+func main() {}
+`,
+			wantErr: true,
+		},
+		{
+			name: "no down",
+			content: `package migration
+
+import "database/sql"
+
+func up(tx *sql.Tx) error { return nil }
+`,
+			wantErr: true,
+		},
+		{
+			name:    "syntax error",
+			content: "package migration\n\nfunc up(",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseGoSource("00001_m.go", []byte(tt.content))
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrWrongFileFormat)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got.Call)
+			require.True(t, strings.HasPrefix(string(got.Files[0].Content), "package main\n"))
+		})
+	}
+}
+
+func TestParseGoPackage(t *testing.T) {
+	files := map[string][]byte{
+		"m/up.go":   []byte("package backfill\n\nimport \"database/sql\"\n\nfunc up(tx *sql.Tx) error { return fill(tx) }\n"),
+		"m/down.go": []byte("package backfill\n\nimport \"database/sql\"\n\nfunc down(tx *sql.Tx) error { return nil }\n"),
+		"m/fill.go": []byte("package backfill\n\nimport \"database/sql\"\n\nfunc fill(tx *sql.Tx) error { return nil }\n"),
+	}
+
+	got, err := parseGoPackage(files)
+	require.NoError(t, err)
+	require.Len(t, got.Files, 3)
+	require.Equal(t, "down.go", got.Files[0].Name)
+
+	files["m/other.go"] = []byte("package other\n")
+	_, err = parseGoPackage(files)
+	require.ErrorIs(t, err, ErrWrongFileFormat)
+
+	delete(files, "m/other.go")
+	files["m/up2.go"] = []byte("package backfill\n\nimport \"database/sql\"\n\nfunc up(tx *sql.Tx) error { return nil }\n")
+	_, err = parseGoPackage(files)
+	require.ErrorIs(t, err, ErrWrongFileFormat)
+}
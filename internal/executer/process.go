@@ -0,0 +1,130 @@
+package executer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	migfile "github.com/dimonk33/sql-migrator/internal/file"
+)
+
+const (
+	// Количество последних строк stderr, добавляемых в ошибку процесса.
+	stderrTailLines = 20
+
+	// Максимальная длина строки вывода, более длинная строка передается частями.
+	maxLineLen = 1024 * 1024
+)
+
+// Время на закрытие вывода после завершения процесса: дочерний процесс, запущенный в фоне,
+// может держать вывод открытым.
+var processWaitDelay = 5 * time.Second
+
+// runProcess запускает процесс, построчно передавая его stdout и stderr в логгер с префиксом.
+// Процесс вместе с дочерними процессами завершается по отмене ctx, ошибка содержит конец stderr.
+// Вывод читает Wait, поэтому ожидание вывода после завершения процесса ограничено WaitDelay.
+func runProcess(ctx context.Context, l migfile.Logger, cmd *exec.Cmd, prefix string) error {
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		return killProcessTree(cmd.Process)
+	}
+	cmd.WaitDelay = processWaitDelay
+
+	tail := &lineTail{max: stderrTailLines}
+	stdout := &lineWriter{fn: func(line string) {
+		l.Info(prefix, line)
+	}}
+	stderr := &lineWriter{fn: func(line string) {
+		l.Error(prefix, line)
+		tail.add(line)
+	}}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err := cmd.Run()
+	stdout.Flush()
+	stderr.Flush()
+
+	if errors.Is(err, exec.ErrWaitDelay) {
+		// Процесс завершился успешно, вывод держит открытым запущенный им фоновый процесс
+		l.Warning(prefix, "вывод не закрыт дочерним процессом после завершения")
+		return nil
+	}
+	if err == nil {
+		return nil
+	}
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		if errors.Is(ctxErr, context.DeadlineExceeded) {
+			err = fmt.Errorf("превышено время выполнения: %w", ctxErr)
+		} else {
+			err = fmt.Errorf("%w: %w", ctxErr, err)
+		}
+	}
+	if s := tail.String(); s != "" {
+		return fmt.Errorf("%w\n%s", err, s)
+	}
+
+	return err
+}
+
+// lineWriter передает записанный вывод в fn построчно.
+type lineWriter struct {
+	fn  func(line string)
+	buf []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i == -1 {
+			break
+		}
+		w.fn(strings.TrimSuffix(string(w.buf[:i]), "\r"))
+		w.buf = w.buf[i+1:]
+	}
+
+	if len(w.buf) >= maxLineLen {
+		w.Flush()
+	}
+
+	return len(p), nil
+}
+
+// Flush передает неполную последнюю строку.
+func (w *lineWriter) Flush() {
+	if len(w.buf) > 0 {
+		w.fn(string(w.buf))
+	}
+	w.buf = nil
+}
+
+// lineTail хранит последние max строк.
+type lineTail struct {
+	mu    sync.Mutex
+	max   int
+	lines []string
+}
+
+func (t *lineTail) add(line string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.lines = append(t.lines, line)
+	if len(t.lines) > t.max {
+		t.lines = t.lines[len(t.lines)-t.max:]
+	}
+}
+
+func (t *lineTail) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return strings.Join(t.lines, "\n")
+}
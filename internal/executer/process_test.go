@@ -0,0 +1,72 @@
+//go:build !windows
+
+package executer
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/dimonk33/sql-migrator/internal/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunProcess(t *testing.T) {
+	l := logger.New(logger.LevelDebug)
+	ctx := context.Background()
+
+	err := runProcess(ctx, l, exec.CommandContext(ctx, "sh", "-c", "echo out; echo first >&2; echo last >&2; exit 3"), "[m]")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "exit status 3")
+	require.Contains(t, err.Error(), "first\nlast")
+
+	require.NoError(t, runProcess(ctx, l, exec.CommandContext(ctx, "sh", "-c", "echo ok"), "[m]"))
+
+	tctx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = runProcess(tctx, l, exec.CommandContext(tctx, "sh", "-c", "sleep 30 & sleep 30"), "[m]")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), 10*time.Second)
+}
+
+func TestRunProcess_BackgroundChild(t *testing.T) {
+	l := logger.New(logger.LevelDebug)
+	ctx := context.Background()
+
+	delay := processWaitDelay
+	processWaitDelay = 200 * time.Millisecond
+	defer func() {
+		processWaitDelay = delay
+	}()
+
+	// Фоновый процесс наследует stdout и держит его открытым после завершения скрипта
+	start := time.Now()
+	require.NoError(t, runProcess(ctx, l, exec.CommandContext(ctx, "sh", "-c", "sleep 6 & echo hi"), "[m]"))
+	require.Less(t, time.Since(start), 3*time.Second)
+
+	start = time.Now()
+	err := runProcess(ctx, l, exec.CommandContext(ctx, "sh", "-c", "sleep 6 & echo fail >&2; exit 2"), "[m]")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "exit status 2")
+	require.Contains(t, err.Error(), "fail")
+	require.Less(t, time.Since(start), 3*time.Second)
+}
+
+func TestLineWriter(t *testing.T) {
+	lines := make([]string, 0)
+	w := &lineWriter{fn: func(line string) {
+		lines = append(lines, line)
+	}}
+
+	_, err := w.Write([]byte("one\r\ntw"))
+	require.NoError(t, err)
+	_, err = w.Write([]byte("o\nthree"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"one", "two"}, lines)
+
+	w.Flush()
+	require.Equal(t, []string{"one", "two", "three"}, lines)
+}
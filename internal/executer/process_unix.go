@@ -0,0 +1,22 @@
+//go:build !windows
+
+package executer
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup запускает процесс в отдельной группе, чтобы завершать его вместе с дочерними.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+func killProcessTree(p *os.Process) error {
+	if err := syscall.Kill(-p.Pid, syscall.SIGKILL); err != nil {
+		return p.Kill()
+	}
+
+	return nil
+}
@@ -0,0 +1,18 @@
+package executer
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+func setProcessGroup(_ *exec.Cmd) {}
+
+// killProcessTree завершает процесс вместе с дочерними через taskkill.
+func killProcessTree(p *os.Process) error {
+	if err := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(p.Pid)).Run(); err != nil {
+		return p.Kill()
+	}
+
+	return nil
+}
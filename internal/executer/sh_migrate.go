@@ -0,0 +1,182 @@
+package executer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	migfile "github.com/dimonk33/sql-migrator/internal/file"
+)
+
+const (
+	// Переменные среды shell миграции.
+	ShDatabaseURLEnv = "DATABASE_URL"
+	ShMigrationEnv   = "GM_MIGRATION"
+	ShDirectionEnv   = "GM_DIRECTION"
+
+	// Значения GM_DIRECTION.
+	ShDirectionUp   = "up"
+	ShDirectionDown = "down"
+
+	// Интерпретатор для скриптов без строки #!.
+	defaultShell = "sh"
+)
+
+// DBSh база для shell миграций: скрипт подключается к базе сам, запись в истории
+// миграций ведется отдельно, как для sql миграций без транзакции.
+type DBSh interface {
+	ConnURL() string
+	ConnEnv() []string
+	Create(ctx context.Context, name string) error
+	SetApplied(ctx context.Context, name string) error
+	Delete(ctx context.Context, name string) error
+}
+
+// ShMigrate выполняет shell миграции: файл с частями Up и Down или пару .up.sh и .down.sh.
+type ShMigrate struct {
+	db     DBSh
+	logger migfile.Logger
+}
+
+func NewShMigrate(db DBSh, l migfile.Logger) *ShMigrate {
+	return &ShMigrate{
+		db:     db,
+		logger: l,
+	}
+}
+
+func (sm *ShMigrate) UpExec(ctx context.Context, path string) error {
+	mName := filepath.Base(path)
+
+	cmd, err := sm.command(ctx, path, UpDirection)
+	if err != nil {
+		return fmt.Errorf("ошибка парсинга файла: %w", err)
+	}
+
+	if err = sm.db.Create(ctx, mName); err != nil {
+		return fmt.Errorf("регистрация миграции: %w", err)
+	}
+
+	if err = runProcess(ctx, sm.logger, cmd, "["+mName+"]"); err != nil {
+		if errD := sm.db.Delete(ctx, mName); errD != nil {
+			sm.logger.Error("удаление записи о миграции:", errD)
+		}
+		return fmt.Errorf("применение миграции: %w", err)
+	}
+
+	if err = sm.db.SetApplied(ctx, mName); err != nil {
+		return fmt.Errorf("закрытие миграции: %w", err)
+	}
+
+	return nil
+}
+
+func (sm *ShMigrate) DownExec(ctx context.Context, path string) error {
+	mName := filepath.Base(path)
+
+	cmd, err := sm.command(ctx, path, DownDirection)
+	if err != nil {
+		return fmt.Errorf("ошибка парсинга файла: %w", err)
+	}
+
+	if err = runProcess(ctx, sm.logger, cmd, "["+mName+"]"); err != nil {
+		return fmt.Errorf("откат миграции: %w", err)
+	}
+
+	if err = sm.db.Delete(ctx, mName); err != nil {
+		return fmt.Errorf("удаление записи о миграции: %w", err)
+	}
+
+	return nil
+}
+
+// command команда запуска миграции в направлении direction. Файл пары запускается целиком,
+// часть файла с разметкой передается интерпретатору через -c вместе с заголовком файла.
+// Интерпретатор задает строка #! файла.
+func (sm *ShMigrate) command(ctx context.Context, path string, direction int) (*exec.Cmd, error) {
+	mName := filepath.Base(path)
+	if migfile.IsSplitUp(path) && direction == DownDirection {
+		path = migfile.SplitDownPath(path)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s", ErrNoData, filepath.Base(path))
+		}
+		return nil, err
+	}
+
+	// Скрипт запускается в каталоге миграции, поэтому путь к файлу пары указывается относительно него
+	args := []string{filepath.Base(path)}
+	if !migfile.IsSplitUp(mName) {
+		script, err := shSection(string(content), direction)
+		if err != nil {
+			return nil, err
+		}
+		args = []string{"-c", script, mName}
+	}
+
+	dir := ShDirectionUp
+	if direction == DownDirection {
+		dir = ShDirectionDown
+	}
+
+	interp := shInterpreter(string(content))
+	cmd := exec.CommandContext(ctx, interp[0], append(interp[1:], args...)...)
+	cmd.Dir = filepath.Dir(path)
+	cmd.Env = append(os.Environ(), sm.db.ConnEnv()...)
+	cmd.Env = append(cmd.Env,
+		ShDatabaseURLEnv+"="+sm.db.ConnURL(),
+		ShMigrationEnv+"="+mName,
+		ShDirectionEnv+"="+dir,
+	)
+
+	return cmd, nil
+}
+
+// shSection скрипт части Up или Down файла shell миграции: заголовок файла до разметки Up
+// и текст части. Заголовок задает общие для частей настройки, например set -eu.
+func shSection(content string, direction int) (string, error) {
+	upPos := strings.Index(content, migfile.ShUpPartID)
+	if upPos == -1 {
+		return "", ErrWrongFileFormat
+	}
+
+	header := content[:upPos]
+	up := content[upPos+len(migfile.ShUpPartID):]
+	down := ""
+	if downPos := strings.Index(up, migfile.ShDownPartID); downPos != -1 {
+		up, down = up[:downPos], up[downPos+len(migfile.ShDownPartID):]
+	}
+
+	var section string
+	switch direction {
+	case UpDirection:
+		section = up
+	case DownDirection:
+		section = down
+	default:
+		return "", ErrWrongDirection
+	}
+
+	if strings.TrimSpace(section) == "" {
+		return "", ErrNoData
+	}
+
+	return header + section, nil
+}
+
+// shInterpreter интерпретатор и его аргументы из строки #! скрипта.
+func shInterpreter(content string) []string {
+	line, _, _ := strings.Cut(content, "\n")
+	fields := strings.Fields(strings.TrimPrefix(line, "#!"))
+	if !strings.HasPrefix(line, "#!") || len(fields) == 0 {
+		return []string{defaultShell}
+	}
+
+	return fields
+}
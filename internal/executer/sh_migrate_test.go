@@ -0,0 +1,133 @@
+package executer
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	migfile "github.com/dimonk33/sql-migrator/internal/file"
+	"github.com/dimonk33/sql-migrator/internal/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShSection(t *testing.T) {
+	content := "#!/bin/sh\nset -eu\n# ===gm Up===\necho up\n# ===gm Down===\necho down\n"
+
+	got, err := shSection(content, UpDirection)
+	require.NoError(t, err)
+	require.Equal(t, "#!/bin/sh\nset -eu\n\necho up\n", got)
+
+	got, err = shSection(content, DownDirection)
+	require.NoError(t, err)
+	require.Equal(t, "#!/bin/sh\nset -eu\n\necho down\n", got)
+
+	_, err = shSection("#!/bin/sh\n# ===gm Up===\necho up\n", DownDirection)
+	require.ErrorIs(t, err, ErrNoData)
+
+	_, err = shSection("echo up\n", UpDirection)
+	require.ErrorIs(t, err, ErrWrongFileFormat)
+}
+
+func TestShInterpreter(t *testing.T) {
+	require.Equal(t, []string{"/usr/bin/env", "bash"}, shInterpreter("#!/usr/bin/env bash\necho\n"))
+	require.Equal(t, []string{"/bin/sh"}, shInterpreter("#!/bin/sh"))
+	require.Equal(t, []string{defaultShell}, shInterpreter("echo\n"))
+	require.Equal(t, []string{defaultShell}, shInterpreter("#!\necho\n"))
+}
+
+type testShDB struct {
+	calls []string
+}
+
+func (db *testShDB) ConnURL() string {
+	return "postgres://gm:@db/app"
+}
+
+func (db *testShDB) ConnEnv() []string {
+	return []string{"PGHOST=db"}
+}
+
+func (db *testShDB) Create(_ context.Context, name string) error {
+	db.calls = append(db.calls, "create "+name)
+	return nil
+}
+
+func (db *testShDB) SetApplied(_ context.Context, name string) error {
+	db.calls = append(db.calls, "applied "+name)
+	return nil
+}
+
+func (db *testShDB) Delete(_ context.Context, name string) error {
+	db.calls = append(db.calls, "delete "+name)
+	return nil
+}
+
+func TestShMigrate_Exec(t *testing.T) {
+	if _, err := exec.LookPath(defaultShell); err != nil {
+		t.Skip("sh не найден")
+	}
+
+	root := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(root, "migrations"), 0o750))
+
+	script := "#!/bin/sh\necho \"$DATABASE_URL $GM_MIGRATION $GM_DIRECTION $PGHOST\"\n"
+	files := map[string]string{
+		"00001_single.sh":    "#!/bin/sh\nset -eu\n" + migfile.ShUpPartID + "\n" + script + migfile.ShDownPartID + "\n" + script,
+		"00002_pair.up.sh":   script,
+		"00002_pair.down.sh": script,
+		"00003_fail.sh":      "#!/bin/sh\n" + migfile.ShUpPartID + "\necho broken >&2\nexit 4\n",
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(root, "migrations", name), []byte(content), 0o600))
+	}
+
+	// Каталог миграций указывается относительно рабочего каталога, как --migrate ./migrations
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(root))
+	defer func() {
+		require.NoError(t, os.Chdir(wd))
+	}()
+
+	db := &testShDB{}
+	sm := NewShMigrate(db, logger.New(logger.LevelDebug))
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		path      string
+		direction int
+		want      string
+	}{
+		{path: "migrations/00001_single.sh", direction: UpDirection, want: "postgres://gm:@db/app 00001_single.sh up db\n"},
+		{path: "migrations/00001_single.sh", direction: DownDirection, want: "postgres://gm:@db/app 00001_single.sh down db\n"},
+		{path: "migrations/00002_pair.up.sh", direction: UpDirection, want: "postgres://gm:@db/app 00002_pair.up.sh up db\n"},
+		{path: "migrations/00002_pair.up.sh", direction: DownDirection, want: "postgres://gm:@db/app 00002_pair.up.sh down db\n"},
+	} {
+		cmd, err := sm.command(ctx, tt.path, tt.direction)
+		require.NoError(t, err)
+
+		out, err := cmd.Output()
+		require.NoError(t, err, tt.path)
+		require.Equal(t, tt.want, string(out))
+	}
+
+	require.NoError(t, sm.UpExec(ctx, "migrations/00002_pair.up.sh"))
+	require.NoError(t, sm.DownExec(ctx, "migrations/00002_pair.up.sh"))
+
+	err = sm.UpExec(ctx, "migrations/00003_fail.sh")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "broken")
+
+	_, err = sm.command(ctx, "migrations/00003_fail.sh", DownDirection)
+	require.ErrorIs(t, err, ErrNoData)
+
+	require.Equal(t, []string{
+		"create 00002_pair.up.sh",
+		"applied 00002_pair.up.sh",
+		"delete 00002_pair.up.sh",
+		"create 00003_fail.sh",
+		"delete 00003_fail.sh",
+	}, db.calls)
+}
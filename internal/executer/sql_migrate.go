@@ -23,6 +23,9 @@ type SQLMigrate struct {
 type DBSQL interface {
 	ApplyTx(ctx context.Context, name string, sqlPool []string) error
 	RevertTx(ctx context.Context, name string, sqlPool []string) error
+	ApplyNoTx(ctx context.Context, name string, sqlPool []string) error
+	RevertNoTx(ctx context.Context, name string, sqlPool []string) error
+	ApplyRepeatableTx(ctx context.Context, name string, checksum string, sqlPool []string) error
 }
 
 var (
@@ -38,18 +41,21 @@ func NewSQLMigrate(db DBSQL) *SQLMigrate {
 }
 
 func (sm *SQLMigrate) UpExec(ctx context.Context, path string) error {
-	text, err := sm.parseFile(path, UpDirection)
+	sec, err := sm.Section(path, UpDirection)
 	if err != nil {
 		return fmt.Errorf("ошибка парсинга файла: %w", err)
 	}
 
-	sqls := sm.extractSQLRequest(text)
-	if len(sqls) == 0 {
+	if len(sec.Statements) == 0 {
 		return ErrNoData
 	}
 
 	name := filepath.Base(path)
-	err = sm.db.ApplyTx(ctx, name, sqls)
+	if sec.NoTx {
+		err = sm.db.ApplyNoTx(ctx, name, sec.Statements)
+	} else {
+		err = sm.db.ApplyTx(ctx, name, sec.Statements)
+	}
 	if err != nil {
 		return fmt.Errorf("ошибка применения миграции %s: %w", path, err)
 	}
@@ -58,18 +64,21 @@ func (sm *SQLMigrate) UpExec(ctx context.Context, path string) error {
 }
 
 func (sm *SQLMigrate) DownExec(ctx context.Context, path string) error {
-	text, err := sm.parseFile(path, DownDirection)
+	sec, err := sm.Section(path, DownDirection)
 	if err != nil {
 		return fmt.Errorf("ошибка парсинга файла: %w", err)
 	}
 
-	sqlList := sm.extractSQLRequest(text)
-	if len(sqlList) == 0 {
+	if len(sec.Statements) == 0 {
 		return ErrNoData
 	}
 
 	name := filepath.Base(path)
-	err = sm.db.RevertTx(ctx, name, sqlList)
+	if sec.NoTx {
+		err = sm.db.RevertNoTx(ctx, name, sec.Statements)
+	} else {
+		err = sm.db.RevertTx(ctx, name, sec.Statements)
+	}
 	if err != nil {
 		return fmt.Errorf("ошибка отката миграции %s: %w", path, err)
 	}
@@ -77,17 +86,61 @@ func (sm *SQLMigrate) DownExec(ctx context.Context, path string) error {
 	return nil
 }
 
-func (sm *SQLMigrate) parseFile(path string, dir int) (string, error) {
+func (sm *SQLMigrate) RepeatExec(ctx context.Context, path string, checksum string) error {
+	sqls, err := sm.RepeatStatements(path)
+	if err != nil {
+		return err
+	}
+
+	name := filepath.Base(path)
+	err = sm.db.ApplyRepeatableTx(ctx, name, checksum, sqls)
+	if err != nil {
+		return fmt.Errorf("ошибка применения повторяемой миграции %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// RepeatStatements запросы повторяемой миграции.
+func (sm *SQLMigrate) RepeatStatements(path string) ([]string, error) {
+	text, err := sm.parseRepeatableFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка парсинга файла: %w", err)
+	}
+
+	sqls := splitBlocks(text, migfile.SQLStatementBeginID, migfile.SQLStatementEndID, splitSQL)
+	if len(sqls) == 0 {
+		return nil, ErrNoData
+	}
+
+	return sqls, nil
+}
+
+// Section разбор миграции с учетом диалекта разметки, в котором она написана.
+func (sm *SQLMigrate) Section(path string, dir int) (SQLSection, error) {
 	fileContent, err := os.ReadFile(path)
 	if err != nil {
-		return "", fmt.Errorf("ошибка открытия файла: %w", err)
+		return SQLSection{}, fmt.Errorf("ошибка открытия файла: %w", err)
+	}
+
+	return ParseSQL(path, string(fileContent), dir)
+}
+
+// ParseSQL разбор содержимого sql миграции, path используется для выбора диалекта
+// и поиска парных файлов.
+func ParseSQL(path string, content string, dir int) (SQLSection, error) {
+	return findSQLDialect(filepath.Base(path), content).Section(path, content, dir)
+}
+
+func parseNativeFile(path string, fileStr string, dir int) (string, error) {
+	if migfile.IsSplitUp(path) {
+		return parseSplitFile(path, fileStr, dir)
 	}
 
-	fileStr := string(fileContent)
 	upStartIndex := strings.Index(fileStr, migfile.SQLUpPartID) + len(migfile.SQLUpPartID)
 	upEndIndex := strings.Index(fileStr, migfile.SQLDownPartID)
 	downStartIndex := upEndIndex + len(migfile.SQLDownPartID)
-	downEndIndex := len(fileContent)
+	downEndIndex := len(fileStr)
 
 	if upStartIndex < len(migfile.SQLUpPartID) || upEndIndex < upStartIndex {
 		return "", ErrWrongFileFormat
@@ -103,14 +156,41 @@ func (sm *SQLMigrate) parseFile(path string, dir int) (string, error) {
 	return "", ErrWrongDirection
 }
 
-func (sm *SQLMigrate) extractSQLRequest(text string) []string {
-	ts := strings.TrimSpace(strings.ReplaceAll(strings.ReplaceAll(text, "\r", ""), "\n", ""))
-	sql := strings.Split(ts, ";")
-	out := make([]string, 0)
-	for _, s := range sql {
-		if s != "" {
-			out = append(out, s)
+// Для пары файлов up часть находится в основном файле, down часть - в парном.
+func parseSplitFile(path string, upContent string, dir int) (string, error) {
+	switch dir {
+	case UpDirection:
+		return upContent, nil
+	case DownDirection:
+		downContent, err := os.ReadFile(migfile.SplitDownPath(path))
+		if err != nil {
+			if os.IsNotExist(err) {
+				return "", ErrWrongFileFormat
+			}
+			return "", fmt.Errorf("ошибка открытия файла: %w", err)
 		}
+		return string(downContent), nil
+	}
+
+	return "", ErrWrongDirection
+}
+
+// Повторяемая миграция может содержать только Up часть или быть без разметки.
+func (sm *SQLMigrate) parseRepeatableFile(path string) (string, error) {
+	fileContent, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("ошибка открытия файла: %w", err)
 	}
-	return out
+
+	fileStr := strings.Replace(string(fileContent), migfile.SQLRepeatableID, "", 1)
+
+	if upStartIndex := strings.Index(fileStr, migfile.SQLUpPartID); upStartIndex != -1 {
+		fileStr = fileStr[upStartIndex+len(migfile.SQLUpPartID):]
+	}
+
+	if downStartIndex := strings.Index(fileStr, migfile.SQLDownPartID); downStartIndex != -1 {
+		fileStr = fileStr[:downStartIndex]
+	}
+
+	return fileStr, nil
 }
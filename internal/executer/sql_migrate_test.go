@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -16,7 +17,7 @@ const (
 	testBadSQLFile  = "555555_bad_sql_migration.sql"
 )
 
-func TestSQLMigrate_parseFile(t *testing.T) {
+func TestParseNativeFile(t *testing.T) {
 	var (
 		srcPath      string
 		testGoodData []byte
@@ -83,21 +84,22 @@ func TestSQLMigrate_parseFile(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			sm := &SQLMigrate{}
-			got, err := sm.parseFile(tt.args.path, tt.args.dir)
+			content, err := os.ReadFile(tt.args.path)
+			require.NoError(t, err)
+
+			got, err := parseNativeFile(tt.args.path, string(content), tt.args.dir)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("parseFile() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("parseNativeFile() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 			if got != tt.want {
-				t.Errorf("parseFile() got = %v, want %v", got, tt.want)
+				t.Errorf("parseNativeFile() got = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
-//nolint:lll
-func TestSQLMigrate_extractSQLRequest(t *testing.T) {
+func TestSplitSQL_Statements(t *testing.T) {
 	type args struct {
 		text string
 	}
@@ -121,7 +123,12 @@ SELECT * FROM test_sql_migration;
 `,
 			},
 			want: []string{
-				"CREATE TABLE test_sql_migration(    id         SERIAL PRIMARY KEY,    name       varchar(255) NOT NULL,    created_at timestamp    NOT NULL default now())",
+				`CREATE TABLE test_sql_migration
+(
+    id         SERIAL PRIMARY KEY,
+    name       varchar(255) NOT NULL,
+    created_at timestamp    NOT NULL default now()
+)`,
 				"DROP TABLE test_sql_migration",
 				"SELECT * FROM test_sql_migration",
 			},
@@ -149,10 +156,71 @@ SELECT * FROM test_sql_migration;;;;;
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			sm := &SQLMigrate{}
-			if got := sm.extractSQLRequest(tt.args.text); !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("extractSQLRequest() = %v, want %v", got, tt.want)
+			if got := splitSQL(tt.args.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitSQL() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestSQLMigrate_parseRepeatableFile(t *testing.T) {
+	testDirName := t.TempDir()
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "plain",
+			content: "CREATE OR REPLACE VIEW v AS SELECT 1;",
+			want:    "CREATE OR REPLACE VIEW v AS SELECT 1;",
+		},
+		{
+			name:    "header",
+			content: migfile.SQLRepeatableID + "\nGRANT SELECT ON t TO r;",
+			want:    "\nGRANT SELECT ON t TO r;",
+		},
+		{
+			name:    "up and down",
+			content: migfile.SQLUpPartID + "\nSELECT 1;\n" + migfile.SQLDownPartID + "\nSELECT 2;",
+			want:    "\nSELECT 1;\n",
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(testDirName, "R__"+strconv.Itoa(i)+".sql")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o600))
+
+			sm := &SQLMigrate{}
+			got, err := sm.parseRepeatableFile(path)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseNativeFile_Split(t *testing.T) {
+	testDirName := t.TempDir()
+
+	upPath := filepath.Join(testDirName, "00001_users.up.sql")
+	require.NoError(t, os.WriteFile(upPath, []byte("CREATE TABLE users();"), 0o600))
+
+	upContent := "CREATE TABLE users();"
+
+	_, err := parseNativeFile(upPath, upContent, DownDirection)
+	require.ErrorIs(t, err, ErrWrongFileFormat)
+
+	require.NoError(t, os.WriteFile(migfile.SplitDownPath(upPath), []byte("DROP TABLE users;"), 0o600))
+
+	got, err := parseNativeFile(upPath, upContent, UpDirection)
+	require.NoError(t, err)
+	require.Equal(t, "CREATE TABLE users();", got)
+
+	got, err = parseNativeFile(upPath, upContent, DownDirection)
+	require.NoError(t, err)
+	require.Equal(t, "DROP TABLE users;", got)
+
+	_, err = parseNativeFile(upPath, upContent, 0)
+	require.ErrorIs(t, err, ErrWrongDirection)
+}
@@ -2,6 +2,8 @@ package migfile
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -10,8 +12,19 @@ import (
 const (
 	SQLFile = "sql"
 	GoFile  = "go"
+	ShFile  = "sh"
+
+	// Суффиксы парных файлов миграции в формате golang-migrate.
+	SQLUpSuffix   = ".up." + SQLFile
+	SQLDownSuffix = ".down." + SQLFile
+
+	// Суффиксы парных файлов shell миграции.
+	ShUpSuffix   = ".up." + ShFile
+	ShDownSuffix = ".down." + ShFile
 )
 
+var ErrOrphanDown = errors.New("отсутствует парный up файл миграции")
+
 type Finder struct{}
 
 func NewFileFinder() (*Finder, error) {
@@ -20,6 +33,7 @@ func NewFileFinder() (*Finder, error) {
 
 func (ff *Finder) ScanDir(ctx context.Context, path string) (map[string]string, error) {
 	list := make(map[string]string)
+	downList := make([]string, 0)
 
 	entries, err := os.ReadDir(path)
 	if err != nil {
@@ -31,19 +45,84 @@ func (ff *Finder) ScanDir(ctx context.Context, path string) (map[string]string,
 		case <-ctx.Done():
 			return nil, ctx.Err()
 		default:
-			if ff.validateEntry(e) {
-				list[e.Name()] = filepath.Join(path, e.Name())
+			if !ff.validateEntry(path, e) {
+				continue
+			}
+
+			// Down файл пары относится к миграции up файла и отдельно не учитывается
+			if IsSplitDown(e.Name()) || IsFlywayUndo(e.Name()) {
+				downList = append(downList, e.Name())
+				continue
 			}
+
+			list[e.Name()] = filepath.Join(path, e.Name())
+		}
+	}
+
+	for _, name := range downList {
+		upName := splitUpName(name)
+		if IsFlywayUndo(name) {
+			upName = flywayVersionedName(name)
+		}
+
+		if _, ok := list[upName]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrOrphanDown, name)
 		}
 	}
 
 	return list, nil
 }
 
-func (ff *Finder) validateEntry(e os.DirEntry) bool {
+// validateEntry проверяет, что элемент каталога - файл миграции или каталог go миграции.
+func (ff *Finder) validateEntry(dir string, e os.DirEntry) bool {
 	if e.IsDir() {
-		return false
+		_, err := ParseVersion(e.Name())
+		return err == nil && IsGoDir(filepath.Join(dir, e.Name()))
 	}
 	ext := strings.ReplaceAll(filepath.Ext(e.Name()), ".", "")
-	return ext == SQLFile || ext == GoFile
+	return ext == SQLFile || ext == GoFile || ext == ShFile
+}
+
+// IsGoDir проверяет, что путь - каталог go миграции: каталог с go файлами, кроме тестов.
+// Такая миграция собирается одним пакетом, кроме go файлов в каталоге могут быть
+// README, каталог testdata и другие файлы.
+func IsGoDir(path string) bool {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return false
+	}
+
+	for _, e := range entries {
+		if IsGoSource(e) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsGoSource проверяет, что элемент каталога go миграции - исходник пакета миграции.
+func IsGoSource(e os.DirEntry) bool {
+	name := e.Name()
+	return e.Type().IsRegular() && filepath.Ext(name) == "."+GoFile && !strings.HasSuffix(name, "_test."+GoFile)
+}
+
+func IsSplitUp(name string) bool {
+	return strings.HasSuffix(name, SQLUpSuffix) || strings.HasSuffix(name, ShUpSuffix)
+}
+
+func IsSplitDown(name string) bool {
+	return strings.HasSuffix(name, SQLDownSuffix) || strings.HasSuffix(name, ShDownSuffix)
+}
+
+// SplitDownPath путь до down файла по пути up файла пары.
+func SplitDownPath(upPath string) string {
+	ext := filepath.Ext(upPath)
+	return strings.TrimSuffix(upPath, ".up"+ext) + ".down" + ext
+}
+
+// splitUpName имя up файла по имени down файла пары.
+func splitUpName(downName string) string {
+	ext := filepath.Ext(downName)
+	return strings.TrimSuffix(downName, ".down"+ext) + ".up" + ext
 }
@@ -126,9 +126,99 @@ func TestFinder_validateEntry(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			ff := &Finder{}
-			if got := ff.validateEntry(tt.args.e); got != tt.want {
+			if got := ff.validateEntry(filepath.Join(srcPath, testDataPath), tt.args.e); got != tt.want {
 				t.Errorf("validateEntry() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestFinder_ScanDirSplit(t *testing.T) {
+	testDirName := t.TempDir()
+
+	for _, name := range []string{"00001_init.up.sql", "00001_init.down.sql", "00002_only_up.up.sql"} {
+		require.NoError(t, os.WriteFile(filepath.Join(testDirName, name), nil, 0o600))
+	}
+
+	ff := &Finder{}
+	got, err := ff.ScanDir(context.Background(), testDirName)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"00001_init.up.sql":    filepath.Join(testDirName, "00001_init.up.sql"),
+		"00002_only_up.up.sql": filepath.Join(testDirName, "00002_only_up.up.sql"),
+	}, got)
+
+	mlist, err := ff.Versioned(got)
+	require.NoError(t, err)
+	require.Len(t, mlist, 2)
+	require.Equal(t, Version{Number: 1, Description: "init"}, mlist[0].Version)
+	require.Equal(t, filepath.Join(testDirName, "00001_init.down.sql"), mlist[0].DownPath)
+	require.Empty(t, mlist[1].DownPath)
+
+	require.NoError(t, os.WriteFile(filepath.Join(testDirName, "00003_orphan.down.sql"), nil, 0o600))
+	_, err = ff.ScanDir(context.Background(), testDirName)
+	require.ErrorIs(t, err, ErrOrphanDown)
+}
+
+func TestFinder_ScanDirFlyway(t *testing.T) {
+	testDirName := t.TempDir()
+
+	for _, name := range []string{"V1__init.sql", "U1__init.sql", "R__views.sql"} {
+		require.NoError(t, os.WriteFile(filepath.Join(testDirName, name), nil, 0o600))
+	}
+
+	ff := &Finder{}
+	got, err := ff.ScanDir(context.Background(), testDirName)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"V1__init.sql": filepath.Join(testDirName, "V1__init.sql"),
+		"R__views.sql": filepath.Join(testDirName, "R__views.sql"),
+	}, got)
+
+	versioned, _, err := ff.SplitRepeatable(got)
+	require.NoError(t, err)
+
+	mlist, err := ff.Versioned(versioned)
+	require.NoError(t, err)
+	require.Len(t, mlist, 1)
+	require.Equal(t, filepath.Join(testDirName, "U1__init.sql"), mlist[0].DownPath)
+
+	require.NoError(t, os.WriteFile(filepath.Join(testDirName, "V1.1__index.sql"), nil, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(testDirName, "U1.1__index.sql"), nil, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(testDirName, "V2_1__data.sql"), nil, 0o600))
+	got, err = ff.ScanDir(context.Background(), testDirName)
+	require.NoError(t, err)
+	versioned, _, err = ff.SplitRepeatable(got)
+	require.NoError(t, err)
+	mlist, err = ff.Versioned(versioned)
+	require.NoError(t, err)
+	require.Len(t, mlist, 3)
+	require.Equal(t, "V1.1__index.sql", mlist[1].Name)
+	require.Equal(t, filepath.Join(testDirName, "U1.1__index.sql"), mlist[1].DownPath)
+	require.Equal(t, "V2_1__data.sql", mlist[2].Name)
+
+	require.NoError(t, os.WriteFile(filepath.Join(testDirName, "U2__orphan.sql"), nil, 0o600))
+	_, err = ff.ScanDir(context.Background(), testDirName)
+	require.ErrorIs(t, err, ErrOrphanDown)
+}
+
+func TestFinder_ScanDirGoDir(t *testing.T) {
+	testDirName := t.TempDir()
+
+	goDir := filepath.Join(testDirName, "00002_backfill")
+	require.NoError(t, os.MkdirAll(filepath.Join(goDir, "testdata"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(goDir, "up.go"), nil, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(goDir, "README"), nil, 0o600))
+
+	testsDir := filepath.Join(testDirName, "00003_tests_only")
+	require.NoError(t, os.Mkdir(testsDir, 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(testsDir, "up_test.go"), nil, 0o600))
+
+	require.NoError(t, os.Mkdir(filepath.Join(testDirName, "fixtures"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(testDirName, "fixtures", "a.go"), nil, 0o600))
+
+	ff := &Finder{}
+	got, err := ff.ScanDir(context.Background(), testDirName)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"00002_backfill": goDir}, got)
+}
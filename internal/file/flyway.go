@@ -0,0 +1,40 @@
+package migfile
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	flywayVersionedPrefix = "V"
+	flywayUndoPrefix      = "U"
+	flywaySeparator       = "__"
+)
+
+var (
+	flywayVersionedRe = regexp.MustCompile(`^V\d+([._]\d+)*__.+\.sql$`)
+	flywayUndoRe      = regexp.MustCompile(`^U\d+([._]\d+)*__.+\.sql$`)
+)
+
+// IsFlywayVersioned имя версионной миграции Flyway: V<версия>__<описание>.sql,
+// части версии разделяются точкой или подчеркиванием: V1__, V1.1__, V2_1__.
+func IsFlywayVersioned(name string) bool {
+	return flywayVersionedRe.MatchString(filepath.Base(name))
+}
+
+// IsFlywayUndo имя отменяющей миграции Flyway: U<версия>__<описание>.sql.
+func IsFlywayUndo(name string) bool {
+	return flywayUndoRe.MatchString(filepath.Base(name))
+}
+
+// FlywayUndoPath путь до отменяющей миграции по пути версионной.
+func FlywayUndoPath(path string) string {
+	base := filepath.Base(path)
+
+	return filepath.Join(filepath.Dir(path), flywayUndoPrefix+strings.TrimPrefix(base, flywayVersionedPrefix))
+}
+
+func flywayVersionedName(undoName string) string {
+	return flywayVersionedPrefix + strings.TrimPrefix(undoName, flywayUndoPrefix)
+}
@@ -0,0 +1,87 @@
+package migfile
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// Префикс имени файла повторяемой миграции (совместим с Flyway).
+	RepeatablePrefix = "R__"
+
+	// Заголовок, которым можно пометить повторяемую миграцию вместо префикса.
+	SQLRepeatableID = "-- ===gm Repeatable==="
+)
+
+var ErrRepeatableType = errors.New("повторяемыми могут быть только sql миграции")
+
+// SplitRepeatable разделяет найденные миграции на версионные и повторяемые.
+func (ff *Finder) SplitRepeatable(list map[string]string) (map[string]string, map[string]string, error) {
+	versioned := make(map[string]string, len(list))
+	repeatable := make(map[string]string)
+
+	for name, path := range list {
+		ok, err := IsRepeatable(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		if ok {
+			repeatable[name] = path
+		} else {
+			versioned[name] = path
+		}
+	}
+
+	return versioned, repeatable, nil
+}
+
+func IsRepeatable(path string) (bool, error) {
+	name := filepath.Base(path)
+	ext := strings.Trim(filepath.Ext(name), ".")
+
+	if strings.HasPrefix(name, RepeatablePrefix) {
+		if ext != SQLFile {
+			return false, ErrRepeatableType
+		}
+		return true, nil
+	}
+
+	if ext != SQLFile {
+		return false, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return false, fmt.Errorf("ошибка открытия файла: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		return line == SQLRepeatableID, nil
+	}
+
+	return false, scanner.Err()
+}
+
+func Checksum(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("ошибка открытия файла: %w", err)
+	}
+
+	sum := sha256.Sum256(content)
+
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,88 @@
+package migfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsRepeatable(t *testing.T) {
+	testDirName := t.TempDir()
+
+	files := map[string]string{
+		"R__views.sql":           "CREATE OR REPLACE VIEW v AS SELECT 1;",
+		"R__helper.go":           "package main",
+		"000001_grants.sql":      "\n" + SQLRepeatableID + "\nGRANT SELECT ON t TO r;",
+		"000002_table.sql":       SQLUpPartID + "\nCREATE TABLE t();\n" + SQLDownPartID + "\nDROP TABLE t;",
+		"000003_go_migrate.go":   "package main",
+		"000004_late_header.sql": "SELECT 1;\n" + SQLRepeatableID,
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(testDirName, name), []byte(content), 0o600))
+	}
+
+	tests := []struct {
+		name    string
+		file    string
+		want    bool
+		wantErr bool
+	}{
+		{name: "prefix", file: "R__views.sql", want: true},
+		{name: "prefix go", file: "R__helper.go", wantErr: true},
+		{name: "header", file: "000001_grants.sql", want: true},
+		{name: "versioned sql", file: "000002_table.sql", want: false},
+		{name: "versioned go", file: "000003_go_migrate.go", want: false},
+		{name: "header not first", file: "000004_late_header.sql", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := IsRepeatable(filepath.Join(testDirName, tt.file))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("IsRepeatable() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("IsRepeatable() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFinder_SplitRepeatable(t *testing.T) {
+	testDirName := t.TempDir()
+
+	list := map[string]string{
+		"R__views.sql":      filepath.Join(testDirName, "R__views.sql"),
+		"000001_table.sql":  filepath.Join(testDirName, "000001_table.sql"),
+		"000002_grants.sql": filepath.Join(testDirName, "000002_grants.sql"),
+	}
+	require.NoError(t, os.WriteFile(list["R__views.sql"], []byte("SELECT 1;"), 0o600))
+	require.NoError(t, os.WriteFile(list["000001_table.sql"], []byte(SQLUpPartID), 0o600))
+	require.NoError(t, os.WriteFile(list["000002_grants.sql"], []byte(SQLRepeatableID), 0o600))
+
+	ff := &Finder{}
+	versioned, repeatable, err := ff.SplitRepeatable(list)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"000001_table.sql": list["000001_table.sql"]}, versioned)
+	require.Equal(t, map[string]string{
+		"R__views.sql":      list["R__views.sql"],
+		"000002_grants.sql": list["000002_grants.sql"],
+	}, repeatable)
+}
+
+func TestChecksum(t *testing.T) {
+	testDirName := t.TempDir()
+	path := filepath.Join(testDirName, "R__views.sql")
+
+	require.NoError(t, os.WriteFile(path, []byte("SELECT 1;"), 0o600))
+	sum1, err := Checksum(path)
+	require.NoError(t, err)
+	require.Len(t, sum1, 64)
+
+	require.NoError(t, os.WriteFile(path, []byte("SELECT 2;"), 0o600))
+	sum2, err := Checksum(path)
+	require.NoError(t, err)
+	require.NotEqual(t, sum1, sum2)
+}
@@ -5,8 +5,6 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"runtime"
-	"strings"
 	"text/template"
 	"time"
 )
@@ -15,15 +13,50 @@ const (
 	SQLUpPartID   = "-- ===gm Up==="
 	SQLDownPartID = "-- ===gm Down==="
 
+	// Разметка блока, который выполняется одним запросом (функции, DO блоки).
+	SQLStatementBeginID = "-- ===gm StatementBegin==="
+	SQLStatementEndID   = "-- ===gm StatementEnd==="
+
+	// Разметка миграции, выполняемой вне транзакции (CREATE INDEX CONCURRENTLY).
+	SQLNoTransactionID = "-- ===gm NoTransaction==="
+
+	// Разметка частей shell миграции в одном файле.
+	ShUpPartID   = "# ===gm Up==="
+	ShDownPartID = "# ===gm Down==="
+
 	GoUpFuncName   = "up"
 	GoDownFuncName = "down"
 
 	GoUpPartID   = "func " + GoUpFuncName + "(tx *sql.Tx) error {"
 	GoDownPartID = "func " + GoDownFuncName + "(tx *sql.Tx) error {"
+
+	// Расположение частей миграции: в одном файле или в паре up/down файлов.
+	LayoutSingle = "single"
+	LayoutSplit  = "split"
+
+	// Версия шаблона запуска go миграции, входит в ключ кэша собранных миграций.
+	// Должна меняться при каждом изменении goMainTemplate и запроса истории, который выполняет запуск.
+	GoTemplateVersion = "8"
+
+	// Файлы пакета сборки go миграции: исходник миграции из одного файла и сгенерированный запуск.
+	GoMigrationFile = "migration.go"
+	GoMainFile      = "gomigrator_main.go"
+
+	// Переменная среды с запросом записи в историю миграций, выполняемым в транзакции миграции.
+	GoHistorySQLEnv = "GM_HISTORY_SQL"
+
+	// Переменные среды с уровнем логирования и переменными (json) для окружения go миграции.
+	GoLogLevelEnv = "GM_LOG_LEVEL"
+	GoVarsEnv     = "GM_VARS"
+
+	// Пакет с окружением go миграции gomigrator.Env.
+	GoEnvPackage = "github.com/dimonk33/sql-migrator/pkg/gomigrator"
 )
 
 type Template struct {
 	tmplDirPath string
+	versioning  string
+	layout      string
 	f           *os.File
 	logger      Logger
 }
@@ -37,24 +70,68 @@ type Logger interface {
 
 type tmplVars struct {
 	MainFunc string
+	Up       string
+	Down     string
 }
 
-type goTmplVars struct {
-	MigrateCode string
-	MigrateFunc string
-	DBConn      string
+// GoCall форма функций up и down go миграции: true, если функция принимает
+// (ctx context.Context, tx *sql.Tx, env *gomigrator.Env).
+type GoCall struct {
+	UpEnv   bool
+	DownEnv bool
 }
 
-type shTmplVars struct {
-	SrcDir string
+type goTmplVars struct {
+	GoCall
+	EnvPackage string
+	UpFunc     string
+	DownFunc   string
+	HistoryEnv string
 }
 
 var sqlMigrateTemplate = template.Must(template.New("gm.sql-migration").Parse(
 	SQLUpPartID + `
-CREATE 'up SQL query';
+{{if .Up}}{{.Up}}{{else}}CREATE 'up SQL query';{{end}}
 
 ` + SQLDownPartID + `
-DROP 'down SQL query';
+{{if .Down}}{{.Down}}{{else}}DROP 'down SQL query';{{end}}
+`))
+
+var sqlUpMigrateTemplate = template.Must(template.New("gm.sql-up-migration").Parse(
+	`{{if .Up}}{{.Up}}{{else}}CREATE 'up SQL query';{{end}}
+`))
+
+var sqlDownMigrateTemplate = template.Must(template.New("gm.sql-down-migration").Parse(
+	`{{if .Down}}{{.Down}}{{else}}DROP 'down SQL query';{{end}}
+`))
+
+var shMigrateTemplate = template.Must(template.New("gm.sh-migration").Parse(
+	`#!/bin/sh
+set -eu
+
+` + ShUpPartID + `
+# Команды применения миграции, подключение к базе: psql "$DATABASE_URL"
+echo "$GM_MIGRATION $GM_DIRECTION"
+
+` + ShDownPartID + `
+# Команды отката миграции
+echo "$GM_MIGRATION $GM_DIRECTION"
+`))
+
+var shUpMigrateTemplate = template.Must(template.New("gm.sh-up-migration").Parse(
+	`#!/bin/sh
+set -eu
+
+# Команды применения миграции, подключение к базе: psql "$DATABASE_URL"
+echo "$GM_MIGRATION $GM_DIRECTION"
+`))
+
+var shDownMigrateTemplate = template.Must(template.New("gm.sh-down-migration").Parse(
+	`#!/bin/sh
+set -eu
+
+# Команды отката миграции
+echo "$GM_MIGRATION $GM_DIRECTION"
 `))
 
 var goMigrateTemplate = template.Must(template.New("gm.go-migration").Parse(
@@ -79,69 +156,142 @@ var goMainTemplate = template.Must(template.New("gm.go-main").Parse(
 	`package main
 
 import (
-	"log"
-	"context"
-	"time"
+	gmcontext "context"
+	gmsql "database/sql"
+	gmerrors "errors"
+	gmlog "log"
+	gmos "os"
+	gmsignal "os/signal"
+	gmsyscall "syscall"
+
 	_ "github.com/lib/pq"
-{{.MigrateCode}}
+{{- if or .UpEnv .DownEnv}}
+	gmenv "{{.EnvPackage}}"
+{{- end}}
+)
 
 func main() {
 	var (
-		db	*sql.DB
-		tx	*sql.Tx
+		db	*gmsql.DB
+		tx	*gmsql.Tx
 		err error
 	)
-	db, err = sql.Open("postgres", "{{.DBConn}}")
+	if len(gmos.Args) < 3 {
+		gmlog.Fatal("не указаны направление и имя миграции")
+	}
+
+	// Параметры подключения передаются в переменных среды PGHOST, PGUSER, PGPASSWORD и др.
+	db, err = gmsql.Open("postgres", "")
 	if err != nil {
-		log.Fatal(err)
+		gmlog.Fatal(err)
 	}
 	db.SetConnMaxLifetime(0)
-	db.SetMaxIdleConns(1)
-	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(2)
+	db.SetMaxOpenConns(2)
+{{- if or .UpEnv .DownEnv}}
+
+	env, err := gmenv.NewEnv(gmos.Args[2], db)
+	if err != nil {
+		gmlog.Fatal(err)
+	}
+{{- end}}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	ctx, cancel := gmsignal.NotifyContext(gmcontext.Background(), gmos.Interrupt, gmsyscall.SIGTERM)
 	defer cancel()
 
-	tx, err = db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	tx, err = db.BeginTx(ctx, &gmsql.TxOptions{Isolation: gmsql.LevelSerializable})
 	if err != nil {
-		log.Fatal(err)
+		gmlog.Fatal(err)
 	}
 
-	err = {{.MigrateFunc}}(tx)
+	switch gmos.Args[1] {
+	case "{{.UpFunc}}":
+		err = {{.UpFunc}}({{if .UpEnv}}ctx, tx, env{{else}}tx{{end}})
+	case "{{.DownFunc}}":
+		err = {{.DownFunc}}({{if .DownEnv}}ctx, tx, env{{else}}tx{{end}})
+	default:
+		err = gmerrors.New("неизвестное направление миграции: " + gmos.Args[1])
+	}
 	if err != nil {
-		log.Fatal(err)
+		_ = tx.Rollback()
+		gmlog.Fatal(err)
 	}
-}
-`))
 
-var shRunTemplate = template.Must(template.New("gm.go-main").Parse(
-	`#!/bin/bash
-
-cd {{.SrcDir}} &&
-go mod init migrate &&
-go mod tidy
-go run .
-`))
+	_, err = tx.ExecContext(ctx, gmos.Getenv("{{.HistoryEnv}}"), gmos.Args[2])
+	if gmerrors.Is(err, gmsql.ErrTxDone) {
+		gmlog.Fatal("транзакцию миграции завершает мигратор, миграция не должна вызывать Commit или Rollback")
+	}
+	if err != nil {
+		_ = tx.Rollback()
+		gmlog.Fatal(err)
+	}
 
-var batRunTemplate = template.Must(template.New("gm.go-main").Parse(
-	`cd /d {{.SrcDir}}
-go mod init migrate
-go mod tidy
-go run .
+	if err = tx.Commit(); err != nil {
+		gmlog.Fatal(err)
+	}
+}
 `))
 
 func NewTemplate(logg Logger, dir string) *Template {
 	return &Template{
 		tmplDirPath: dir,
+		versioning:  VersionTimestamp,
+		layout:      LayoutSingle,
 		logger:      logg,
 	}
 }
 
+func (t *Template) SetVersioning(v string) error {
+	if err := ValidateVersioning(v); err != nil {
+		return err
+	}
+
+	t.versioning = v
+
+	return nil
+}
+
+func ValidateLayout(l string) error {
+	if l != LayoutSingle && l != LayoutSplit {
+		return fmt.Errorf("неизвестное расположение миграции: %s", l)
+	}
+
+	return nil
+}
+
+func (t *Template) SetLayout(l string) error {
+	if err := ValidateLayout(l); err != nil {
+		return err
+	}
+
+	t.layout = l
+
+	return nil
+}
+
 func (t *Template) Create(name string, tType string) (string, error) {
-	fname := time.Now().Format("20060102150405") + "_" + name + "." + tType
+	return t.create(name, tType, tmplVars{})
+}
+
+// CreateSQL создает sql миграцию с заданными запросами Up и Down частей.
+func (t *Template) CreateSQL(name string, up string, down string) (string, error) {
+	return t.create(name, SQLFile, tmplVars{Up: up, Down: down})
+}
+
+func (t *Template) create(name string, tType string, tv tmplVars) (string, error) {
+	version, err := t.nextVersion()
+	if err != nil {
+		return "", fmt.Errorf("ошибка определения версии: %w", err)
+	}
+
+	if t.layout == LayoutSplit {
+		return t.createSplit(version+"_"+name, tType, tv)
+	}
+
+	fname := version + "_" + name + "." + tType
 	path := filepath.Join(t.tmplDirPath, fname)
 
-	_, err := os.Stat(path)
+	_, err = os.Stat(path)
 	if !os.IsNotExist(err) {
 		return "", fmt.Errorf("ошибка создания файла: %w", err)
 	}
@@ -158,15 +308,13 @@ func (t *Template) Create(name string, tType string) (string, error) {
 		}
 	}()
 
-	tv := tmplVars{
-		MainFunc: "",
-	}
-
 	switch tType {
 	case SQLFile:
 		err = sqlMigrateTemplate.Execute(t.f, tv)
 	case GoFile:
 		err = goMigrateTemplate.Execute(t.f, tv)
+	case ShFile:
+		err = shMigrateTemplate.Execute(t.f, tv)
 	default:
 		err = errors.New("неподдерживаемый тип миграций")
 	}
@@ -178,25 +326,77 @@ func (t *Template) Create(name string, tType string) (string, error) {
 	return fname, nil
 }
 
-func (t *Template) CreateGoMain(content string, callFuncName string, dbConn string) (string, error) {
-	const importPrefix = "import ("
-	_, err := os.Stat(t.tmplDirPath)
-	if err != nil {
-		return "", fmt.Errorf("ошибка наличия каталога: %w", err)
+func (t *Template) createSplit(baseName string, tType string, tv tmplVars) (string, error) {
+	type part struct {
+		fname string
+		tmpl  *template.Template
 	}
 
-	importPos := strings.Index(content, importPrefix)
-	if importPos == -1 {
-		return "", fmt.Errorf("неверный формат")
+	var parts []part
+	switch tType {
+	case SQLFile:
+		parts = []part{
+			{fname: baseName + SQLUpSuffix, tmpl: sqlUpMigrateTemplate},
+			{fname: baseName + SQLDownSuffix, tmpl: sqlDownMigrateTemplate},
+		}
+	case ShFile:
+		parts = []part{
+			{fname: baseName + ShUpSuffix, tmpl: shUpMigrateTemplate},
+			{fname: baseName + ShDownSuffix, tmpl: shDownMigrateTemplate},
+		}
+	default:
+		return "", errors.New("парные файлы поддерживаются только для sql и shell миграций")
 	}
 
-	validContent := strings.TrimLeft(content[importPos+len(importPrefix):], "\r\n")
+	upName := parts[0].fname
 
-	fname := filepath.Join(t.tmplDirPath, "main.go")
+	// Пара создается целиком: файлы проверяются до записи, при ошибке созданные файлы удаляются,
+	// иначе одиночный up файл будет принят за миграцию
+	for _, p := range parts {
+		if err := checkNotExist(filepath.Join(t.tmplDirPath, p.fname)); err != nil {
+			return "", err
+		}
+	}
 
-	t.f, err = os.Create(fname)
+	created := make([]string, 0, len(parts))
+	for _, p := range parts {
+		path := filepath.Join(t.tmplDirPath, p.fname)
+		if err := t.createFromTemplate(path, p.tmpl, tv); err != nil {
+			for _, c := range append(created, path) {
+				if errR := os.Remove(c); errR != nil && !os.IsNotExist(errR) {
+					t.logger.Warning("ошибка удаления файла: " + errR.Error())
+				}
+			}
+			return "", err
+		}
+		created = append(created, path)
+	}
+
+	return upName, nil
+}
+
+// checkNotExist проверяет, что файл миграции еще не создан.
+func checkNotExist(path string) error {
+	_, err := os.Stat(path)
+	if err == nil {
+		return fmt.Errorf("ошибка создания файла: %w: %s", os.ErrExist, path)
+	}
+	if !os.IsNotExist(err) {
+		return fmt.Errorf("ошибка создания файла: %w", err)
+	}
+
+	return nil
+}
+
+func (t *Template) createFromTemplate(path string, tmpl *template.Template, tv tmplVars) error {
+	if err := checkNotExist(path); err != nil {
+		return err
+	}
+
+	var err error
+	t.f, err = os.Create(path)
 	if err != nil {
-		return "", fmt.Errorf("ошибка создания файла: %w", err)
+		return fmt.Errorf("ошибка создания файла: %w", err)
 	}
 
 	defer func() {
@@ -206,39 +406,37 @@ func (t *Template) CreateGoMain(content string, callFuncName string, dbConn stri
 		}
 	}()
 
-	tv := goTmplVars{
-		MigrateCode: validContent,
-		MigrateFunc: callFuncName,
-		DBConn:      dbConn,
+	if err = tmpl.Execute(t.f, tv); err != nil {
+		return fmt.Errorf("ошибка генерации шаблона: %w", err)
 	}
 
-	err = goMainTemplate.Execute(t.f, tv)
+	return nil
+}
 
+func (t *Template) nextVersion() (string, error) {
+	if t.versioning != VersionSequential {
+		return formatTimestamp(time.Now()), nil
+	}
+
+	versions, err := dirVersions(t.tmplDirPath)
 	if err != nil {
-		return "", fmt.Errorf("ошибка генерации шаблона: %w", err)
+		return "", err
 	}
 
-	return fname, nil
+	return FormatSequential(LastSequential(versions) + 1), nil
 }
 
-func (t *Template) CreateRunSh() (string, error) {
+// CreateGoMain создает main файл в пакете сборки go миграции, исходники которой
+// уже переведены в пакет main. Собранная миграция выполняет функцию up или down по первому аргументу.
+// Параметры подключения собранная миграция получает из переменных среды libpq,
+// поэтому не содержит учетных данных, не зависит от базы и может быть закэширована.
+func (t *Template) CreateGoMain(call GoCall) (string, error) {
 	_, err := os.Stat(t.tmplDirPath)
 	if err != nil {
 		return "", fmt.Errorf("ошибка наличия каталога: %w", err)
 	}
 
-	var fname string
-	var tmpl *template.Template
-	switch runtime.GOOS {
-	case "linux":
-		fname = filepath.Join(t.tmplDirPath, "run.sh")
-		tmpl = shRunTemplate
-	case "windows":
-		fname = filepath.Join(t.tmplDirPath, "run.bat")
-		tmpl = batRunTemplate
-	default:
-		return "", fmt.Errorf("неподдерживаемая система: %s", runtime.GOOS)
-	}
+	fname := filepath.Join(t.tmplDirPath, GoMainFile)
 
 	t.f, err = os.Create(fname)
 	if err != nil {
@@ -252,16 +450,15 @@ func (t *Template) CreateRunSh() (string, error) {
 		}
 	}()
 
-	err = t.f.Chmod(os.ModePerm)
-	if err != nil {
-		return "", fmt.Errorf("ошибка смены прав доступа файла: %w", err)
-	}
-
-	tv := shTmplVars{
-		SrcDir: t.tmplDirPath,
+	tv := goTmplVars{
+		GoCall:     call,
+		EnvPackage: GoEnvPackage,
+		UpFunc:     GoUpFuncName,
+		DownFunc:   GoDownFuncName,
+		HistoryEnv: GoHistorySQLEnv,
 	}
 
-	err = tmpl.Execute(t.f, tv)
+	err = goMainTemplate.Execute(t.f, tv)
 
 	if err != nil {
 		return "", fmt.Errorf("ошибка генерации шаблона: %w", err)
@@ -3,10 +3,8 @@ package migfile
 import (
 	"os"
 	"path/filepath"
-	"runtime"
 	"strings"
 	"testing"
-	"text/template"
 
 	"github.com/dimonk33/sql-migrator/internal/logger"
 	"github.com/stretchr/testify/require"
@@ -107,39 +105,8 @@ func TestTemplate_CreateGoMain(t1 *testing.T) {
 
 	logg := logger.New(logger.LevelDebug)
 
-	testContent := `import (
-	"database/sql"
-)
-
-const (
-	tableName = "test_go_migration"
-)
-
-func up(tx *sql.Tx) error {
-	return tx.Commit()
-}
-
-func down(tx *sql.Tx) error {
-	return tx.Commit()
-}`
-	testControlContent := `	"database/sql"
-)
-
-const (
-	tableName = "test_go_migration"
-)
-
-func up(tx *sql.Tx) error {
-	return tx.Commit()
-}
-
-func down(tx *sql.Tx) error {
-	return tx.Commit()
-}`
 	type args struct {
-		content      string
-		callFuncName string
-		dbConn       string
+		call GoCall
 	}
 	tests := []struct {
 		name    string
@@ -155,23 +122,19 @@ func down(tx *sql.Tx) error {
 				logger:      logg,
 			},
 			args: args{
-				content:      testContent,
-				callFuncName: "up",
-				dbConn:       "test connection",
+				call: GoCall{UpEnv: true},
 			},
-			want:    filepath.Join(testDirName, "main.go"),
+			want:    filepath.Join(testDirName, GoMainFile),
 			wantErr: false,
 		},
 		{
 			name: "test fail",
 			fields: fields{
-				tmplDirPath: testDirName,
+				tmplDirPath: filepath.Join(testDirName, "missing"),
 				logger:      logg,
 			},
 			args: args{
-				content:      "test content",
-				callFuncName: "up",
-				dbConn:       "test connection",
+				call: GoCall{UpEnv: true},
 			},
 			want:    "",
 			wantErr: true,
@@ -185,7 +148,7 @@ func down(tx *sql.Tx) error {
 			}
 
 			var got string
-			got, err = t.CreateGoMain(tt.args.content, tt.args.callFuncName, tt.args.dbConn)
+			got, err = t.CreateGoMain(tt.args.call)
 			if (err != nil) != tt.wantErr {
 				t1.Errorf("CreateGoMain() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -202,93 +165,18 @@ func down(tx *sql.Tx) error {
 				fileContent, err = os.ReadFile(got)
 				require.NoError(t1, err)
 				tv := goTmplVars{
-					MigrateCode: testControlContent,
-					MigrateFunc: tt.args.callFuncName,
-					DBConn:      tt.args.dbConn,
+					GoCall:     tt.args.call,
+					EnvPackage: GoEnvPackage,
+					UpFunc:     GoUpFuncName,
+					DownFunc:   GoDownFuncName,
+					HistoryEnv: GoHistorySQLEnv,
 				}
 
 				builder := strings.Builder{}
 				err = goMainTemplate.Execute(&builder, tv)
 				require.NoError(t1, err)
 				require.Equal(t1, builder.String(), string(fileContent))
-			}
-		})
-	}
-
-	require.NoError(t1, os.RemoveAll(testDirName))
-}
-
-func TestTemplate_CreateRunSh(t1 *testing.T) {
-	type fields struct {
-		tmplDirPath string
-		logger      Logger
-	}
-
-	testDirName, err := os.MkdirTemp("", "TestTemplate_CreateGoMain")
-	require.NoError(t1, err)
-
-	logg := logger.New(logger.LevelDebug)
-
-	type args struct{}
-	tests := []struct {
-		name    string
-		fields  fields
-		args    args
-		want    string
-		wantErr bool
-	}{
-		{
-			name: "test ok",
-			fields: fields{
-				tmplDirPath: testDirName,
-				logger:      logg,
-			},
-			wantErr: false,
-		},
-	}
-	for _, tt := range tests {
-		t1.Run(tt.name, func(t1 *testing.T) {
-			t := &Template{
-				tmplDirPath: tt.fields.tmplDirPath,
-				logger:      tt.fields.logger,
-			}
-
-			var got string
-
-			got, err = t.CreateRunSh()
-			if (err != nil) != tt.wantErr {
-				t1.Errorf("CreateRunSh() error = %v, wantErr %v", err, tt.wantErr)
-				return
-			}
-			if got == "" {
-				t1.Errorf("CreateRunSh() got = %v, want %v", got, tt.want)
-				return
-			}
-
-			if tt.wantErr {
-				require.Error(t1, err)
-			} else {
-				var fileContent []byte
-				fileContent, err = os.ReadFile(got)
-				require.NoError(t1, err)
-
-				var tmpl *template.Template
-
-				switch runtime.GOOS {
-				case "linux":
-					tmpl = shRunTemplate
-				case "windows":
-					tmpl = batRunTemplate
-				}
-
-				tv := shTmplVars{
-					SrcDir: tt.fields.tmplDirPath,
-				}
-
-				builder := strings.Builder{}
-				err = tmpl.Execute(&builder, tv)
-				require.NoError(t1, err)
-				require.Equal(t1, builder.String(), string(fileContent))
+				require.Contains(t1, string(fileContent), "err = up(ctx, tx, env)")
 			}
 		})
 	}
@@ -0,0 +1,232 @@
+package migfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const versionSeparator = "_"
+
+var (
+	ErrWrongVersion     = errors.New("имя файла не начинается с номера версии")
+	ErrDuplicateVersion = errors.New("повторяющийся номер версии")
+)
+
+// Version номер версии миграции, разобранный из имени файла.
+type Version struct {
+	Number uint64
+	// Следующие части версии Flyway: V1.2.3__ - Number 1, Parts [2 3].
+	// Нулевые части в конце отбрасываются, V1.0__ и V1__ - одна версия.
+	Parts       []uint64
+	Description string
+}
+
+type Migration struct {
+	Name     string
+	Path     string
+	DownPath string
+	Version  Version
+}
+
+func ParseVersion(name string) (Version, error) {
+	base := filepath.Base(name)
+	base = strings.TrimSuffix(base, fileExt(base))
+
+	separator := versionSeparator
+	flyway := IsFlywayVersioned(name) || IsFlywayUndo(name)
+	if flyway {
+		base = base[1:]
+		separator = flywaySeparator
+	}
+
+	i := digitsLen(base)
+	if i == 0 {
+		return Version{}, fmt.Errorf("%w: %s", ErrWrongVersion, name)
+	}
+
+	// Части версии Flyway разделяются точкой или одним подчеркиванием
+	for flyway && i < len(base) && (base[i] == '.' || (base[i] == '_' && !strings.HasPrefix(base[i:], separator))) {
+		n := digitsLen(base[i+1:])
+		if n == 0 {
+			break
+		}
+		i += 1 + n
+	}
+
+	rest := base[i:]
+	if rest != "" && !strings.HasPrefix(rest, separator) {
+		return Version{}, fmt.Errorf("%w: %s", ErrWrongVersion, name)
+	}
+
+	v, err := ParseVersionNumber(base[:i])
+	if err != nil {
+		return Version{}, fmt.Errorf("%w: %s: %w", ErrWrongVersion, name, err)
+	}
+	v.Description = strings.TrimPrefix(rest, separator)
+
+	return v, nil
+}
+
+// ParseVersionNumber разбирает номер версии без описания: 42, 1.1 или 2_1.
+func ParseVersionNumber(s string) (Version, error) {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == '.' || r == '_'
+	})
+	if len(fields) == 0 || strings.Count(s, ".")+strings.Count(s, "_") != len(fields)-1 {
+		return Version{}, fmt.Errorf("неверный номер версии: %s", s)
+	}
+
+	numbers := make([]uint64, 0, len(fields))
+	for _, f := range fields {
+		if digitsLen(f) != len(f) {
+			return Version{}, fmt.Errorf("неверный номер версии: %s", s)
+		}
+
+		n, err := strconv.ParseUint(f, 10, 64)
+		if err != nil {
+			return Version{}, err
+		}
+		numbers = append(numbers, n)
+	}
+
+	for len(numbers) > 1 && numbers[len(numbers)-1] == 0 {
+		numbers = numbers[:len(numbers)-1]
+	}
+
+	v := Version{Number: numbers[0]}
+	if len(numbers) > 1 {
+		v.Parts = numbers[1:]
+	}
+
+	return v, nil
+}
+
+func digitsLen(s string) int {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+
+	return i
+}
+
+func (v Version) Compare(other Version) int {
+	switch {
+	case v.Number < other.Number:
+		return -1
+	case v.Number > other.Number:
+		return 1
+	}
+
+	for i := 0; i < len(v.Parts) || i < len(other.Parts); i++ {
+		var a, b uint64
+		if i < len(v.Parts) {
+			a = v.Parts[i]
+		}
+		if i < len(other.Parts) {
+			b = other.Parts[i]
+		}
+
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+	}
+
+	return 0
+}
+
+func (v Version) String() string {
+	builder := strings.Builder{}
+	builder.WriteString(strconv.FormatUint(v.Number, 10))
+	for _, p := range v.Parts {
+		builder.WriteString("." + strconv.FormatUint(p, 10))
+	}
+
+	return builder.String()
+}
+
+// Versioned разбирает версии найденных миграций и сортирует их по возрастанию.
+func (ff *Finder) Versioned(list map[string]string) ([]Migration, error) {
+	out := make([]Migration, 0, len(list))
+	known := make(map[string]string, len(list))
+
+	for name, path := range list {
+		v, err := ParseVersion(name)
+		if err != nil {
+			return nil, err
+		}
+
+		if prev, ok := known[v.String()]; ok {
+			return nil, fmt.Errorf("%w %s: %s, %s", ErrDuplicateVersion, v, prev, name)
+		}
+		known[v.String()] = name
+
+		mg := Migration{
+			Name:    name,
+			Path:    path,
+			Version: v,
+		}
+
+		var downPath string
+		switch {
+		case IsSplitUp(name):
+			downPath = SplitDownPath(path)
+		case IsFlywayVersioned(name):
+			downPath = FlywayUndoPath(path)
+		}
+
+		if downPath != "" {
+			if _, err = os.Stat(downPath); err == nil {
+				mg.DownPath = downPath
+			}
+		}
+
+		out = append(out, mg)
+	}
+
+	SortMigrations(out)
+
+	return out, nil
+}
+
+// Расширение файла с учетом суффикса парных миграций.
+func fileExt(name string) string {
+	switch {
+	case IsSplitUp(name):
+		return ".up" + filepath.Ext(name)
+	case IsSplitDown(name):
+		return ".down" + filepath.Ext(name)
+	}
+
+	return filepath.Ext(name)
+}
+
+func SortMigrations(list []Migration) {
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Version.Compare(list[j].Version) < 0
+	})
+}
+
+// FindVersion ищет миграцию по номеру версии, записанному строкой.
+func FindVersion(mlist []Migration, version string) (Migration, bool) {
+	v, err := ParseVersionNumber(version)
+	if err != nil {
+		return Migration{}, false
+	}
+
+	for _, mg := range mlist {
+		if mg.Version.Compare(v) == 0 {
+			return mg, true
+		}
+	}
+
+	return Migration{}, false
+}
@@ -0,0 +1,183 @@
+package migfile
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		want    Version
+		wantErr bool
+	}{
+		{
+			name: "timestamp",
+			file: "20230601120000_create_users.sql",
+			want: Version{Number: 20230601120000, Description: "create_users"},
+		},
+		{
+			name: "short prefix",
+			file: "/migrations/9_go_migration.go",
+			want: Version{Number: 9, Description: "go_migration"},
+		},
+		{
+			name: "split up file",
+			file: "00007_add_users.up.sql",
+			want: Version{Number: 7, Description: "add_users"},
+		},
+		{
+			name: "flyway versioned",
+			file: "V12__add_orders.sql",
+			want: Version{Number: 12, Description: "add_orders"},
+		},
+		{
+			name: "flyway dotted",
+			file: "V1.1__add_index.sql",
+			want: Version{Number: 1, Parts: []uint64{1}, Description: "add_index"},
+		},
+		{
+			name: "flyway underscored",
+			file: "V2_1_3__fix.sql",
+			want: Version{Number: 2, Parts: []uint64{1, 3}, Description: "fix"},
+		},
+		{
+			name: "flyway trailing zero",
+			file: "U3.0__undo.sql",
+			want: Version{Number: 3, Description: "undo"},
+		},
+		{
+			name: "without description",
+			file: "000042.sql",
+			want: Version{Number: 42},
+		},
+		{
+			name:    "without version",
+			file:    "create_users.sql",
+			wantErr: true,
+		},
+		{
+			name:    "version without separator",
+			file:    "123abc.sql",
+			wantErr: true,
+		},
+		{
+			name:    "version overflow",
+			file:    "999999999999999999999_big.sql",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseVersion(tt.file)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseVersion() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				require.True(t, errors.Is(err, ErrWrongVersion))
+				return
+			}
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFinder_Versioned(t *testing.T) {
+	tests := []struct {
+		name    string
+		list    map[string]string
+		want    []string
+		wantErr error
+	}{
+		{
+			name: "numeric order",
+			list: map[string]string{
+				"10_ten.sql":    "/m/10_ten.sql",
+				"9_nine.go":     "/m/9_nine.go",
+				"100_hundr.sql": "/m/100_hundr.sql",
+			},
+			want: []string{"9_nine.go", "10_ten.sql", "100_hundr.sql"},
+		},
+		{
+			name: "flyway dotted order",
+			list: map[string]string{
+				"V1.10__c.sql": "/m/V1.10__c.sql",
+				"V1.2__b.sql":  "/m/V1.2__b.sql",
+				"V2__d.sql":    "/m/V2__d.sql",
+				"V1__a.sql":    "/m/V1__a.sql",
+			},
+			want: []string{"V1__a.sql", "V1.2__b.sql", "V1.10__c.sql", "V2__d.sql"},
+		},
+		{
+			name: "flyway same version",
+			list: map[string]string{
+				"V1.1__a.sql": "/m/V1.1__a.sql",
+				"V1_1__b.sql": "/m/V1_1__b.sql",
+			},
+			wantErr: ErrDuplicateVersion,
+		},
+		{
+			name: "duplicate version",
+			list: map[string]string{
+				"0010_a.sql": "/m/0010_a.sql",
+				"10_b.go":    "/m/10_b.go",
+			},
+			wantErr: ErrDuplicateVersion,
+		},
+		{
+			name: "wrong version",
+			list: map[string]string{
+				"create.sql": "/m/create.sql",
+			},
+			wantErr: ErrWrongVersion,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ff := &Finder{}
+			got, err := ff.Versioned(tt.list)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			names := make([]string, 0, len(got))
+			for _, mg := range got {
+				require.Equal(t, tt.list[mg.Name], mg.Path)
+				names = append(names, mg.Name)
+			}
+			require.Equal(t, tt.want, names)
+		})
+	}
+}
+
+func TestFindVersion(t *testing.T) {
+	mlist := []Migration{
+		{Name: "00001_init.sql", Version: Version{Number: 1, Description: "init"}},
+		{Name: "V2__users.sql", Version: Version{Number: 2, Description: "users"}},
+		{Name: "V2.1__orders.sql", Version: Version{Number: 2, Parts: []uint64{1}, Description: "orders"}},
+	}
+
+	got, ok := FindVersion(mlist, "2.1")
+	require.True(t, ok)
+	require.Equal(t, "V2.1__orders.sql", got.Name)
+
+	got, ok = FindVersion(mlist, "2")
+	require.True(t, ok)
+	require.Equal(t, "V2__users.sql", got.Name)
+
+	got, ok = FindVersion(mlist, "0001")
+	require.True(t, ok)
+	require.Equal(t, "00001_init.sql", got.Name)
+
+	_, ok = FindVersion(mlist, "1.1")
+	require.False(t, ok)
+
+	_, ok = FindVersion(mlist, "3")
+	require.False(t, ok)
+}
@@ -0,0 +1,199 @@
+package migfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	VersionTimestamp  = "timestamp"
+	VersionSequential = "sequential"
+
+	timestampFormat  = "20060102150405"
+	sequentialFormat = "%05d"
+
+	// Версии из 14 и более цифр считаются отметками времени.
+	minTimestampVersion = 10000000000000
+)
+
+type Renamed struct {
+	From string
+	To   string
+}
+
+func ValidateVersioning(v string) error {
+	if v != VersionTimestamp && v != VersionSequential {
+		return fmt.Errorf("неизвестная схема версионирования: %s", v)
+	}
+
+	return nil
+}
+
+func (v Version) IsTimestamp() bool {
+	return v.Number >= minTimestampVersion
+}
+
+func FormatSequential(n uint64) string {
+	return fmt.Sprintf(sequentialFormat, n)
+}
+
+func formatTimestamp(t time.Time) string {
+	return t.Format(timestampFormat)
+}
+
+// LastSequential возвращает наибольший последовательный номер среди версий.
+func LastSequential(versions []Version) uint64 {
+	var last uint64
+	for _, v := range versions {
+		if !v.IsTimestamp() && v.Number > last {
+			last = v.Number
+		}
+	}
+
+	return last
+}
+
+// FixStart номер, после которого нумеруются миграции при переводе на последовательную
+// нумерацию: наибольший последовательный номер каталога и истории или наибольшая
+// примененная версия, даже если это отметка времени. Иначе перенумерованные миграции
+// окажутся ниже уже примененных и будут применяться вне очереди.
+func FixStart(versions []Version, applied []Version) uint64 {
+	last := LastSequential(versions)
+	for _, v := range applied {
+		if v.Number > last {
+			last = v.Number
+		}
+	}
+
+	return last
+}
+
+// FixPlan переименование непримененных миграций с отметкой времени
+// в последовательные номера после last. Миграции, номер которых уже совпадает
+// с назначенным, не переименовываются, поэтому повторный запуск ничего не меняет.
+func FixPlan(mlist []Migration, applied map[string]struct{}, last uint64) []Renamed {
+	out := make([]Renamed, 0)
+	for _, mg := range mlist {
+		// Имена Flyway задают версию в своем формате и не перенумеровываются
+		if _, ok := applied[mg.Name]; ok || !mg.Version.IsTimestamp() || IsFlywayVersioned(mg.Name) {
+			continue
+		}
+
+		last++
+		number := FormatSequential(last)
+		if mg.Version.Number == last {
+			continue
+		}
+
+		out = append(out, Renamed{
+			From: mg.Name,
+			To:   renameVersion(mg.Name, mg.Version, number),
+		})
+
+		if mg.DownPath != "" {
+			downName := filepath.Base(mg.DownPath)
+			out = append(out, Renamed{
+				From: downName,
+				To:   renameVersion(downName, mg.Version, number),
+			})
+		}
+	}
+
+	return out
+}
+
+func renameVersion(name string, v Version, number string) string {
+	if v.Description == "" {
+		return number + fileExt(name)
+	}
+
+	return number + versionSeparator + v.Description + fileExt(name)
+}
+
+// Суффикс временного имени файла при переименовании.
+const fixTempSuffix = ".gmfix"
+
+// ApplyFix переименовывает файлы согласно плану. Все новые имена проверяются до первого
+// переименования, файлы переименовываются через временные имена, и при ошибке
+// уже переименованные файлы возвращаются к прежним именам.
+func ApplyFix(dir string, plan []Renamed) error {
+	from := make(map[string]struct{}, len(plan))
+	for _, r := range plan {
+		from[r.From] = struct{}{}
+	}
+
+	targets := make(map[string]struct{}, len(plan))
+	for _, r := range plan {
+		if _, ok := targets[r.To]; ok {
+			return fmt.Errorf("файл %s указан в плане повторно", r.To)
+		}
+		targets[r.To] = struct{}{}
+
+		if _, err := os.Stat(filepath.Join(dir, r.From)); err != nil {
+			return fmt.Errorf("ошибка переименования %s: %w", r.From, err)
+		}
+
+		// Имя может освободиться переименованием другого файла плана
+		if _, ok := from[r.To]; ok {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, r.To)); !os.IsNotExist(err) {
+			return fmt.Errorf("файл %s уже существует", r.To)
+		}
+	}
+
+	// moved[i]: 1 - файл плана i переименован во временный, 2 - в новое имя
+	moved := make([]int, len(plan))
+	rollback := func() {
+		for i := len(plan) - 1; i >= 0; i-- {
+			r := plan[i]
+			switch moved[i] {
+			case 1:
+				_ = os.Rename(filepath.Join(dir, r.From+fixTempSuffix), filepath.Join(dir, r.From))
+			case 2:
+				_ = os.Rename(filepath.Join(dir, r.To), filepath.Join(dir, r.From))
+			}
+		}
+	}
+
+	for i, r := range plan {
+		if err := os.Rename(filepath.Join(dir, r.From), filepath.Join(dir, r.From+fixTempSuffix)); err != nil {
+			rollback()
+			return fmt.Errorf("ошибка переименования %s: %w", r.From, err)
+		}
+		moved[i] = 1
+	}
+
+	for i, r := range plan {
+		if err := os.Rename(filepath.Join(dir, r.From+fixTempSuffix), filepath.Join(dir, r.To)); err != nil {
+			rollback()
+			return fmt.Errorf("ошибка переименования %s: %w", r.From, err)
+		}
+		moved[i] = 2
+	}
+
+	return nil
+}
+
+func dirVersions(dir string) ([]Version, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]Version, 0, len(entries))
+	for _, e := range entries {
+		// Каталог с go файлами - go миграция, остальные каталоги версий не имеют
+		if e.IsDir() && !IsGoDir(filepath.Join(dir, e.Name())) {
+			continue
+		}
+
+		if v, err := ParseVersion(e.Name()); err == nil {
+			out = append(out, v)
+		}
+	}
+
+	return out, nil
+}
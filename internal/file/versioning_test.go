@@ -0,0 +1,212 @@
+package migfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dimonk33/sql-migrator/internal/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFixPlan(t *testing.T) {
+	mlist := []Migration{
+		{Name: "00001_init.sql", Version: Version{Number: 1, Description: "init"}},
+		{Name: "00002_users.go", Version: Version{Number: 2, Description: "users"}},
+		{Name: "20230101120000_applied.sql", Version: Version{Number: 20230101120000, Description: "applied"}},
+		{Name: "20230601120000_orders.sql", Version: Version{Number: 20230601120000, Description: "orders"}},
+		{Name: "20230701120000.go", Version: Version{Number: 20230701120000}},
+		{
+			Name:     "20230801120000_split.up.sql",
+			DownPath: "/m/20230801120000_split.down.sql",
+			Version:  Version{Number: 20230801120000, Description: "split"},
+		},
+	}
+	applied := map[string]struct{}{
+		"00001_init.sql":             {},
+		"20230101120000_applied.sql": {},
+	}
+
+	got := FixPlan(mlist, applied, 7)
+	require.Equal(t, []Renamed{
+		{From: "20230601120000_orders.sql", To: "00008_orders.sql"},
+		{From: "20230701120000.go", To: "00009.go"},
+		{From: "20230801120000_split.up.sql", To: "00010_split.up.sql"},
+		{From: "20230801120000_split.down.sql", To: "00010_split.down.sql"},
+	}, got)
+}
+
+func TestFixStart(t *testing.T) {
+	versions := []Version{{Number: 3}, {Number: 20230601120000}, {Number: 12}}
+
+	require.Equal(t, uint64(12), FixStart(versions, []Version{{Number: 3}}))
+	// Примененная отметка времени: нумерация продолжается после нее
+	require.Equal(t, uint64(20230101120000), FixStart(versions, []Version{{Number: 3}, {Number: 20230101120000}}))
+
+	mlist := []Migration{
+		{Name: "20230101120000_applied.sql", Version: Version{Number: 20230101120000, Description: "applied"}},
+		{Name: "20230601120000_orders.sql", Version: Version{Number: 20230601120000, Description: "orders"}},
+	}
+	applied := map[string]struct{}{"20230101120000_applied.sql": {}}
+
+	plan := FixPlan(mlist, applied, FixStart([]Version{mlist[0].Version, mlist[1].Version}, []Version{mlist[0].Version}))
+	require.Equal(t, []Renamed{{From: "20230601120000_orders.sql", To: "20230101120001_orders.sql"}}, plan)
+
+	// Повторный запуск после перенумерации ничего не меняет
+	mlist[1] = Migration{Name: "20230101120001_orders.sql", Version: Version{Number: 20230101120001, Description: "orders"}}
+	require.Empty(t, FixPlan(mlist, applied, 20230101120000))
+}
+
+func TestLastSequential(t *testing.T) {
+	require.Equal(t, uint64(0), LastSequential(nil))
+	require.Equal(t, uint64(12), LastSequential([]Version{
+		{Number: 3},
+		{Number: 20230601120000},
+		{Number: 12},
+	}))
+}
+
+func TestApplyFix(t *testing.T) {
+	testDirName := t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(testDirName, "20230601120000_a.sql"), nil, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(testDirName, "00001_b.sql"), nil, 0o600))
+
+	err := ApplyFix(testDirName, []Renamed{{From: "20230601120000_a.sql", To: "00002_a.sql"}})
+	require.NoError(t, err)
+	_, err = os.Stat(filepath.Join(testDirName, "00002_a.sql"))
+	require.NoError(t, err)
+
+	err = ApplyFix(testDirName, []Renamed{{From: "00002_a.sql", To: "00001_b.sql"}})
+	require.Error(t, err)
+}
+
+func TestApplyFix_Validate(t *testing.T) {
+	testDirName := t.TempDir()
+
+	for _, name := range []string{"20230601120000_a.sql", "20230701120000_b.sql", "00003_c.sql"} {
+		require.NoError(t, os.WriteFile(filepath.Join(testDirName, name), nil, 0o600))
+	}
+
+	// Второе имя занято: первый файл не переименовывается
+	err := ApplyFix(testDirName, []Renamed{
+		{From: "20230601120000_a.sql", To: "00002_a.sql"},
+		{From: "20230701120000_b.sql", To: "00003_c.sql"},
+	})
+	require.Error(t, err)
+
+	entries, err := os.ReadDir(testDirName)
+	require.NoError(t, err)
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	require.Equal(t, []string{"00003_c.sql", "20230601120000_a.sql", "20230701120000_b.sql"}, names)
+
+	// Повторное новое имя
+	err = ApplyFix(testDirName, []Renamed{
+		{From: "20230601120000_a.sql", To: "00004_a.sql"},
+		{From: "20230701120000_b.sql", To: "00004_a.sql"},
+	})
+	require.Error(t, err)
+
+	// Обмен именами выполняется через временные имена
+	err = ApplyFix(testDirName, []Renamed{
+		{From: "20230601120000_a.sql", To: "20230701120000_b.sql"},
+		{From: "20230701120000_b.sql", To: "20230601120000_a.sql"},
+	})
+	require.NoError(t, err)
+}
+
+func TestTemplate_CreateSequential(t *testing.T) {
+	testDirName := t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(testDirName, "00003_a.sql"), nil, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(testDirName, "20230601120000_b.sql"), nil, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(testDirName, "R__views.sql"), nil, 0o600))
+
+	tmpl := NewTemplate(logger.New(logger.LevelDebug), testDirName)
+	require.Error(t, tmpl.SetVersioning("semver"))
+	require.NoError(t, tmpl.SetVersioning(VersionSequential))
+
+	fname, err := tmpl.Create("next", SQLFile)
+	require.NoError(t, err)
+	require.Equal(t, "00004_next.sql", fname)
+
+	fname, err = tmpl.Create("after", GoFile)
+	require.NoError(t, err)
+	require.Equal(t, "00005_after.go", fname)
+}
+
+func TestTemplate_CreateSequentialGoDir(t *testing.T) {
+	testDirName := t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(testDirName, "00003_a.sql"), nil, 0o600))
+	require.NoError(t, os.MkdirAll(filepath.Join(testDirName, "00007_seed"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(testDirName, "00007_seed", "up.go"), []byte("package seed\n"), 0o600))
+	// Каталог без go файлов миграцией не является
+	require.NoError(t, os.MkdirAll(filepath.Join(testDirName, "00009_data"), 0o750))
+
+	tmpl := NewTemplate(logger.New(logger.LevelDebug), testDirName)
+	require.NoError(t, tmpl.SetVersioning(VersionSequential))
+
+	fname, err := tmpl.Create("next", SQLFile)
+	require.NoError(t, err)
+	require.Equal(t, "00008_next.sql", fname)
+}
+
+func TestTemplate_CreateSplit(t *testing.T) {
+	testDirName := t.TempDir()
+
+	tmpl := NewTemplate(logger.New(logger.LevelDebug), testDirName)
+	require.NoError(t, tmpl.SetVersioning(VersionSequential))
+	require.NoError(t, tmpl.SetLayout(LayoutSplit))
+
+	fname, err := tmpl.Create("users", SQLFile)
+	require.NoError(t, err)
+	require.Equal(t, "00001_users.up.sql", fname)
+
+	_, err = os.Stat(filepath.Join(testDirName, "00001_users.down.sql"))
+	require.NoError(t, err)
+
+	_, err = tmpl.Create("users", GoFile)
+	require.Error(t, err)
+
+	// Занятый down файл: up файл пары не создается
+	downPath := filepath.Join(testDirName, "00009_dup.down.sql")
+	require.NoError(t, os.WriteFile(downPath, []byte("-- old\n"), 0o600))
+
+	_, err = tmpl.createSplit("00009_dup", SQLFile, tmplVars{})
+	require.ErrorIs(t, err, os.ErrExist)
+
+	_, err = os.Stat(filepath.Join(testDirName, "00009_dup.up.sql"))
+	require.True(t, os.IsNotExist(err))
+
+	content, err := os.ReadFile(downPath)
+	require.NoError(t, err)
+	require.Equal(t, "-- old\n", string(content))
+}
+
+func TestTemplate_CreateSQL(t *testing.T) {
+	testDirName := t.TempDir()
+
+	tmpl := NewTemplate(logger.New(logger.LevelDebug), testDirName)
+	require.NoError(t, tmpl.SetVersioning(VersionSequential))
+
+	fname, err := tmpl.CreateSQL("users", "CREATE TABLE users (id int);", "DROP TABLE users;")
+	require.NoError(t, err)
+	require.Equal(t, "00001_users.sql", fname)
+
+	content, err := os.ReadFile(filepath.Join(testDirName, fname))
+	require.NoError(t, err)
+	require.Equal(t, SQLUpPartID+"\nCREATE TABLE users (id int);\n\n"+SQLDownPartID+"\nDROP TABLE users;\n", string(content))
+
+	require.NoError(t, tmpl.SetLayout(LayoutSplit))
+	fname, err = tmpl.CreateSQL("orders", "CREATE TABLE orders (id int);", "DROP TABLE orders;")
+	require.NoError(t, err)
+	require.Equal(t, "00002_orders.up.sql", fname)
+
+	content, err = os.ReadFile(filepath.Join(testDirName, "00002_orders.down.sql"))
+	require.NoError(t, err)
+	require.Equal(t, "DROP TABLE orders;\n", string(content))
+}
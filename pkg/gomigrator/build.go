@@ -0,0 +1,55 @@
+package gomigrator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dimonk33/sql-migrator/internal/executer"
+	migfile "github.com/dimonk33/sql-migrator/internal/file"
+)
+
+// BuildResult результат сборки go миграции.
+type BuildResult struct {
+	Name string
+	Path string
+}
+
+// SetGoCacheDir задает каталог кэша собранных go миграций, пустой каталог - каталог по умолчанию.
+func (m *Migrator) SetGoCacheDir(dir string) {
+	if dir == "" {
+		dir = executer.DefaultGoCacheDir()
+	}
+
+	m.goCacheDir = dir
+}
+
+// Build собирает непримененные go миграции в кэш, чтобы up не тратил время на компиляцию.
+// Без подключения к базе собираются все go миграции каталога.
+func (m *Migrator) Build() ([]BuildResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+	defer cancel()
+
+	pending, _, err := m.planned(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	gm := executer.NewGoMigrate(m.db, m.logger)
+	gm.SetCacheDir(m.goCacheDir)
+	gm.SetToolchain(m.goBin, m.goRoot)
+
+	out := make([]BuildResult, 0)
+	for _, mg := range pending {
+		if migrateType(mg.Path) != migfile.GoFile {
+			continue
+		}
+
+		path, err := gm.Build(ctx, mg.Path)
+		if err != nil {
+			return out, fmt.Errorf("миграция %s: %w", mg.Name, err)
+		}
+		out = append(out, BuildResult{Name: mg.Name, Path: path})
+	}
+
+	return out, nil
+}
@@ -0,0 +1,127 @@
+package gomigrator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	migdb "github.com/dimonk33/sql-migrator/internal/db"
+)
+
+// CompareReport различия двух баз: миграции, примененные только в одной из них,
+// повторяемые миграции с разным содержимым и различия схем. В Schema добавленные
+// объекты есть только во второй базе, удаленные - только в первой.
+type CompareReport struct {
+	OnlyFirst  []MigrateStatus
+	OnlySecond []MigrateStatus
+	Repeatable []string
+	Schema     []CatalogChange
+}
+
+func (r *CompareReport) Empty() bool {
+	return len(r.OnlyFirst) == 0 && len(r.OnlySecond) == 0 && len(r.Repeatable) == 0 && len(r.Schema) == 0
+}
+
+// Compare сравнивает состояние миграций и схему баз мигратора m и other.
+func (m *Migrator) Compare(other *Migrator) (*CompareReport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+	defer cancel()
+
+	first, err := m.compareState(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	second, err := other.compareState(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CompareReport{
+		OnlyFirst:  onlyIn(first.applied, second.applied),
+		OnlySecond: onlyIn(second.applied, first.applied),
+		Repeatable: differentRepeatable(first.repeatable, second.repeatable),
+		Schema:     migdb.DiffCatalog(first.catalog, second.catalog),
+	}, nil
+}
+
+type compareState struct {
+	applied    []MigrateStatus
+	repeatable []RepeatableStatus
+	catalog    migdb.Catalog
+}
+
+func (m *Migrator) compareState(ctx context.Context) (*compareState, error) {
+	if err := m.connectReadOnly(ctx); err != nil {
+		return nil, err
+	}
+
+	var (
+		s   compareState
+		err error
+	)
+
+	if s.catalog, err = m.db.Snapshot(ctx); err != nil {
+		return nil, fmt.Errorf("ошибка снимка схемы: %w", err)
+	}
+
+	// В базе без истории миграций ни одна миграция не применена
+	exists, err := m.db.HistoryExists(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка проверки таблицы истории: %w", err)
+	}
+	if !exists {
+		return &s, nil
+	}
+
+	if s.applied, err = m.db.FindAllApplied(ctx); err != nil {
+		return nil, fmt.Errorf("ошибка получения примененных миграций: %w", err)
+	}
+
+	if s.repeatable, err = m.db.FindAllRepeatable(ctx); err != nil {
+		return nil, fmt.Errorf("ошибка получения повторяемых миграций: %w", err)
+	}
+
+	return &s, nil
+}
+
+func onlyIn(list []MigrateStatus, other []MigrateStatus) []MigrateStatus {
+	names := make(map[string]struct{}, len(other))
+	for _, mg := range other {
+		names[mg.Name] = struct{}{}
+	}
+
+	out := make([]MigrateStatus, 0)
+	for _, mg := range list {
+		if _, ok := names[mg.Name]; !ok {
+			out = append(out, mg)
+		}
+	}
+
+	return out
+}
+
+// Повторяемые миграции, которые применены только в одной базе или с другой контрольной суммой.
+func differentRepeatable(list []RepeatableStatus, other []RepeatableStatus) []string {
+	checksums := make(map[string]string, len(list))
+	for _, r := range list {
+		checksums[r.Name] = r.Checksum
+	}
+
+	out := make([]string, 0)
+	for _, r := range other {
+		checksum, ok := checksums[r.Name]
+		if !ok || checksum != r.Checksum {
+			out = append(out, r.Name)
+		}
+		delete(checksums, r.Name)
+	}
+
+	for name := range checksums {
+		out = append(out, name)
+	}
+
+	sort.Strings(out)
+
+	return out
+}
@@ -0,0 +1,36 @@
+package gomigrator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrator_CompareReadOnly(t *testing.T) {
+	first, _ := newTestMigrator(t, nil, []string{"00001_a.sql"})
+	second, _ := newTestMigrator(t, nil, []string{})
+	firstDB, secondDB := first.db.(*testDB), second.db.(*testDB)
+	first.dbReadOnly, second.dbReadOnly = true, true
+	secondDB.noHistory = true
+
+	report, err := first.Compare(second)
+	require.NoError(t, err)
+	require.Equal(t, []string{"00001_a.sql"}, migrationNamesOf(report.OnlyFirst))
+	require.Empty(t, report.OnlySecond)
+	require.Zero(t, firstDB.inits)
+	require.Zero(t, secondDB.inits)
+
+	// Операция, которая пишет в базу, создает служебные таблицы на том же подключении
+	require.NoError(t, first.connect(context.Background()))
+	require.NoError(t, first.connect(context.Background()))
+	require.Equal(t, 1, firstDB.inits)
+}
+
+func migrationNamesOf(list []MigrateStatus) []string {
+	out := make([]string, 0, len(list))
+	for _, mg := range list {
+		out = append(out, mg.Name)
+	}
+	return out
+}
@@ -0,0 +1,61 @@
+package gomigrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	migdb "github.com/dimonk33/sql-migrator/internal/db"
+	"github.com/dimonk33/sql-migrator/internal/executer"
+	migfile "github.com/dimonk33/sql-migrator/internal/file"
+)
+
+var ErrNoDiff = errors.New("схема базы совпадает с желаемой")
+
+// CreateFromDiff создает sql миграцию, которая переводит текущую схему базы в схему
+// из файла desiredPath. Желаемая схема применяется во временной схеме в откатываемой транзакции.
+func (m *Migrator) CreateFromDiff(migrateName string, desiredPath string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+	defer cancel()
+
+	if err := m.connectReadOnly(ctx); err != nil {
+		return "", err
+	}
+
+	content, err := os.ReadFile(desiredPath)
+	if err != nil {
+		return "", fmt.Errorf("ошибка открытия файла: %w", err)
+	}
+
+	current, desired, err := m.db.DesiredSnapshot(ctx, executer.SplitStatements(string(content)))
+	if err != nil {
+		return "", fmt.Errorf("ошибка применения желаемой схемы: %w", err)
+	}
+
+	up := migdb.DiffScript(current, desired)
+	if len(up) == 0 {
+		return "", ErrNoDiff
+	}
+	down := migdb.DiffScript(desired, current)
+
+	if err = os.MkdirAll(m.dirPath, 0o750); err != nil {
+		return "", fmt.Errorf("ошибка создания каталога для миграций: %w", err)
+	}
+
+	t := migfile.NewTemplate(m.logger, m.dirPath)
+	if err = t.SetVersioning(m.versioning); err != nil {
+		return "", err
+	}
+
+	if err = t.SetLayout(m.layout); err != nil {
+		return "", err
+	}
+
+	var fname string
+	if fname, err = t.CreateSQL(migrateName, executer.JoinStatements(up), executer.JoinStatements(down)); err != nil {
+		return "", fmt.Errorf("ошибка создания миграции: %w", err)
+	}
+
+	return fname, nil
+}
@@ -0,0 +1,70 @@
+package gomigrator
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	migfile "github.com/dimonk33/sql-migrator/internal/file"
+	"github.com/dimonk33/sql-migrator/internal/logger"
+)
+
+// Env окружение go миграции с сигнатурой
+// func up(ctx context.Context, tx *sql.Tx, env *gomigrator.Env) error.
+type Env struct {
+	// Логгер с уровнем логирования мигратора.
+	Logger Logger
+	// Имя файла миграции.
+	Name string
+	// Переменные, заданные мигратору флагом --var.
+	Vars map[string]string
+	// Подключение к базе вне транзакции миграции.
+	DB *sql.DB
+}
+
+// NewEnv создает окружение в собранной go миграции из переменных среды, заданных мигратором.
+func NewEnv(name string, db *sql.DB) (*Env, error) {
+	env := &Env{
+		Logger: logger.New(os.Getenv(migfile.GoLogLevelEnv)),
+		Name:   name,
+		Vars:   make(map[string]string),
+		DB:     db,
+	}
+
+	if vars := os.Getenv(migfile.GoVarsEnv); vars != "" {
+		if err := json.Unmarshal([]byte(vars), &env.Vars); err != nil {
+			return nil, fmt.Errorf("переменные миграции: %w", err)
+		}
+	}
+
+	return env, nil
+}
+
+// Var значение переменной миграции или def, если переменная не задана.
+func (e *Env) Var(name string, def string) string {
+	if v, ok := e.Vars[name]; ok {
+		return v
+	}
+
+	return def
+}
+
+// SetGoEnv задает уровень логирования и переменные для окружения go миграций.
+func (m *Migrator) SetGoEnv(logLevel string, vars map[string]string) {
+	m.goLogLevel = logLevel
+	m.goVars = vars
+}
+
+// SetGoTimeout задает время выполнения одной go миграции, 0 - без ограничения.
+// По истечении времени процесс миграции завершается вместе с дочерними процессами.
+func (m *Migrator) SetGoTimeout(d time.Duration) {
+	m.goTimeout = d
+}
+
+// SetGoToolchain задает go для сборки миграций: путь к go и GOROOT, пустые значения - по умолчанию.
+func (m *Migrator) SetGoToolchain(goBin string, goRoot string) {
+	m.goBin = goBin
+	m.goRoot = goRoot
+}
@@ -0,0 +1,156 @@
+package gomigrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	migdb "github.com/dimonk33/sql-migrator/internal/db"
+	"github.com/dimonk33/sql-migrator/internal/executer"
+	migfile "github.com/dimonk33/sql-migrator/internal/file"
+)
+
+var ErrGoMigrationExport = errors.New("go и shell миграции не могут быть экспортированы в sql")
+
+// ExportSQL записывает непримененные sql миграции с версиями от from до to включительно
+// в один скрипт, который можно выполнить через psql. Пустая граница не ограничивает диапазон.
+// Миграции вне очереди проверяются по той же политике, что и в Up. Без верхней границы
+// в конец скрипта, как и в Up, добавляются измененные повторяемые миграции.
+// Без подключения к базе экспортируются все миграции диапазона и все повторяемые миграции.
+func (m *Migrator) ExportSQL(from string, to string, w io.Writer) error {
+	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+	defer cancel()
+
+	lower, upper, err := parseVersionRange(from, to)
+	if err != nil {
+		return err
+	}
+
+	mlist, rlist, err := m.scanDir(ctx)
+	if err != nil {
+		return err
+	}
+
+	pending := mlist
+	changed := make(map[string]repeatableFile, len(rlist))
+	if m.IsOffline() {
+		for name, path := range rlist {
+			checksum, err := migfile.Checksum(path)
+			if err != nil {
+				return fmt.Errorf("ошибка расчета контрольной суммы %s: %w", path, err)
+			}
+			changed[name] = repeatableFile{Path: path, Checksum: checksum}
+		}
+	} else {
+		if err = m.connect(ctx); err != nil {
+			return err
+		}
+
+		var outOfOrder []migfile.Migration
+		pending, outOfOrder, err = m.pending(ctx, mlist)
+		if err != nil {
+			return err
+		}
+
+		if err = m.checkOutOfOrder(outOfOrder); err != nil {
+			return err
+		}
+
+		if changed, err = m.changedRepeatable(ctx, rlist); err != nil {
+			return err
+		}
+	}
+
+	// Повторяемые миграции применяются после всех версионных
+	if upper != nil {
+		changed = map[string]repeatableFile{}
+	}
+
+	export := make([]migfile.Migration, 0, len(pending))
+	goList := make([]string, 0)
+	for _, mg := range pending {
+		if (lower != nil && mg.Version.Compare(*lower) < 0) || (upper != nil && mg.Version.Compare(*upper) > 0) {
+			continue
+		}
+
+		if migrateType(mg.Path) != SQLMigration {
+			goList = append(goList, mg.Name)
+		}
+
+		export = append(export, mg)
+	}
+
+	if len(goList) > 0 {
+		return fmt.Errorf("%w: %s", ErrGoMigrationExport, strings.Join(goList, ", "))
+	}
+
+	if len(export) == 0 && len(changed) == 0 {
+		return ErrNoMigrations
+	}
+
+	sqlExecuter := executer.NewSQLMigrate(m.db)
+	sections := make([]executer.SQLSection, 0, len(export))
+	for _, mg := range export {
+		sec, err := sqlExecuter.Section(mg.Path, executer.UpDirection)
+		if err != nil {
+			return fmt.Errorf("ошибка парсинга миграции %s: %w", mg.Name, err)
+		}
+		sections = append(sections, sec)
+	}
+
+	repeatable := make([][]string, 0, len(changed))
+	for _, k := range sortedKeys(changed) {
+		sqls, err := sqlExecuter.RepeatStatements(changed[k].Path)
+		if err != nil {
+			return fmt.Errorf("ошибка парсинга повторяемой миграции %s: %w", k, err)
+		}
+		repeatable = append(repeatable, sqls)
+	}
+
+	if err = migdb.WriteInitScript(w); err != nil {
+		return fmt.Errorf("ошибка записи скрипта: %w", err)
+	}
+
+	for i, mg := range export {
+		if err = migdb.WriteApplyScript(w, mg.Name, sections[i].Statements, sections[i].NoTx); err != nil {
+			return fmt.Errorf("ошибка записи скрипта: %w", err)
+		}
+	}
+
+	for i, k := range sortedKeys(changed) {
+		if err = migdb.WriteRepeatableScript(w, k, changed[k].Checksum, repeatable[i]); err != nil {
+			return fmt.Errorf("ошибка записи скрипта: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// parseVersionRange границы диапазона версий, nil - граница не задана.
+func parseVersionRange(from string, to string) (*migfile.Version, *migfile.Version, error) {
+	var lower, upper *migfile.Version
+
+	if from != "" {
+		v, err := migfile.ParseVersionNumber(from)
+		if err != nil {
+			return nil, nil, fmt.Errorf("неверная начальная версия %s: %w", from, err)
+		}
+		lower = &v
+	}
+
+	if to != "" {
+		v, err := migfile.ParseVersionNumber(to)
+		if err != nil {
+			return nil, nil, fmt.Errorf("неверная конечная версия %s: %w", to, err)
+		}
+		upper = &v
+	}
+
+	if lower != nil && upper != nil && lower.Compare(*upper) > 0 {
+		return nil, nil, fmt.Errorf("начальная версия %s больше конечной %s", from, to)
+	}
+
+	return lower, upper, nil
+}
@@ -0,0 +1,98 @@
+package gomigrator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	migdb "github.com/dimonk33/sql-migrator/internal/db"
+	migfile "github.com/dimonk33/sql-migrator/internal/file"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrator_ExportSQLRange(t *testing.T) {
+	files := []string{"00001_a.sql", "00002_b.sql", "00003_c.sql", "00004_d.sql"}
+
+	tests := []struct {
+		name    string
+		from    string
+		to      string
+		want    []string
+		wantErr error
+	}{
+		{name: "all", want: files},
+		{name: "closed range", from: "2", to: "3", want: []string{"00002_b.sql", "00003_c.sql"}},
+		{name: "from only", from: "0004", want: []string{"00004_d.sql"}},
+		{name: "to only", to: "1", want: []string{"00001_a.sql"}},
+		{name: "empty range", from: "5", wantErr: ErrNoMigrations},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, _ := newTestMigrator(t, files, nil)
+
+			builder := strings.Builder{}
+			err := m.ExportSQL(tt.from, tt.to, &builder)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			for _, name := range files {
+				require.Equal(t, contains(tt.want, name), strings.Contains(builder.String(), "-- Миграция: "+name), name)
+			}
+		})
+	}
+}
+
+func TestMigrator_ExportSQLErrors(t *testing.T) {
+	m, _ := newTestMigrator(t, []string{"V1__a.sql", "V1.1__b.sql", "V2__c.sql"}, nil)
+
+	builder := strings.Builder{}
+	require.NoError(t, m.ExportSQL("1.1", "1.5", &builder))
+	require.Contains(t, builder.String(), "-- Миграция: V1.1__b.sql")
+	require.NotContains(t, builder.String(), "V1__a.sql")
+	require.NotContains(t, builder.String(), "V2__c.sql")
+
+	require.Error(t, m.ExportSQL("2", "1", &builder))
+	require.Error(t, m.ExportSQL("x", "", &builder))
+
+	require.NoError(t, os.WriteFile(filepath.Join(m.dirPath, "3_go.go"), []byte("package m\n"), 0o600))
+	require.ErrorIs(t, m.ExportSQL("3", "", &builder), ErrGoMigrationExport)
+}
+
+func TestMigrator_ExportSQLPolicy(t *testing.T) {
+	files := []string{"00001_a.sql", "00002_b.sql", "00003_c.sql"}
+	applied := []string{"00001_a.sql", "00003_c.sql"}
+
+	m, _ := newTestMigrator(t, files, applied)
+	builder := strings.Builder{}
+	require.ErrorIs(t, m.ExportSQL("", "", &builder), ErrOutOfOrder)
+	require.Empty(t, builder.String())
+
+	require.NoError(t, m.SetOutOfOrderPolicy(OutOfOrderAllow))
+	require.NoError(t, m.ExportSQL("", "", &builder))
+	require.Contains(t, builder.String(), "-- Миграция: 00002_b.sql")
+	require.NotContains(t, builder.String(), "-- Миграция: 00003_c.sql")
+}
+
+func TestMigrator_ExportSQLRepeatable(t *testing.T) {
+	m, _ := newTestMigrator(t, []string{"00001_a.sql"}, []string{"00001_a.sql"})
+	path := filepath.Join(m.dirPath, "R__views.sql")
+	require.NoError(t, os.WriteFile(path, []byte("CREATE OR REPLACE VIEW v AS SELECT 1;"), 0o600))
+
+	builder := strings.Builder{}
+	require.NoError(t, m.ExportSQL("", "", &builder))
+	require.Contains(t, builder.String(), "-- Повторяемая миграция: R__views.sql")
+	require.Contains(t, builder.String(), "CREATE OR REPLACE VIEW v AS SELECT 1;")
+
+	// С верхней границей повторяемые миграции не экспортируются
+	require.ErrorIs(t, m.ExportSQL("", "1", &builder), ErrNoMigrations)
+
+	// Без изменений повторяемая миграция не экспортируется
+	checksum, err := migfile.Checksum(path)
+	require.NoError(t, err)
+	m.db.(*testDB).repeatable = []migdb.RepeatableInfo{{Name: "R__views.sql", Checksum: checksum}}
+	require.ErrorIs(t, m.ExportSQL("", "", &builder), ErrNoMigrations)
+}
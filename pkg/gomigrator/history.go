@@ -0,0 +1,98 @@
+package gomigrator
+
+import (
+	"context"
+	"fmt"
+
+	migdb "github.com/dimonk33/sql-migrator/internal/db"
+	migfile "github.com/dimonk33/sql-migrator/internal/file"
+)
+
+const (
+	HistoryGoose   = migdb.HistoryGoose
+	HistoryMigrate = migdb.HistoryMigrate
+	HistoryFlyway  = migdb.HistoryFlyway
+)
+
+// ImportReport результат импорта истории из другого инструмента.
+type ImportReport struct {
+	Imported  []string
+	Existing  []string
+	Unmatched []string
+}
+
+// ImportHistory переносит примененные версии из таблицы goose, golang-migrate или Flyway
+// в gomigrate_info, сопоставляя их с файлами миграций в каталоге.
+// Пустое имя таблицы означает таблицу инструмента по умолчанию.
+func (m *Migrator) ImportHistory(source string, table string) (*ImportReport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+	defer cancel()
+
+	if err := m.connect(ctx); err != nil {
+		return nil, err
+	}
+
+	if table == "" {
+		var err error
+		if table, err = migdb.HistoryTable(source); err != nil {
+			return nil, err
+		}
+	}
+
+	mlist, _, err := m.scanDir(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := m.db.ReadHistory(ctx, source, table)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения таблицы %s: %w", table, err)
+	}
+
+	report := &ImportReport{}
+	rows := make([]migdb.MigrateInfo, 0, len(records))
+	for _, rec := range records {
+		mg, ok := migfile.FindVersion(mlist, rec.Version)
+		if !ok {
+			report.Unmatched = append(report.Unmatched, rec.Version)
+		}
+
+		if rec.UpTo {
+			limit, err := migfile.ParseVersionNumber(rec.Version)
+			if err != nil {
+				continue
+			}
+
+			for _, item := range mlist {
+				if item.Version.Compare(limit) <= 0 {
+					rows = append(rows, migdb.MigrateInfo{Name: item.Name, UpdatedAt: rec.AppliedAt})
+				}
+			}
+			continue
+		}
+
+		if ok {
+			rows = append(rows, migdb.MigrateInfo{Name: mg.Name, UpdatedAt: rec.AppliedAt})
+		}
+	}
+
+	imported, err := m.db.ImportApplied(ctx, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	report.Imported = imported
+
+	done := make(map[string]struct{}, len(imported))
+	for _, name := range imported {
+		done[name] = struct{}{}
+	}
+
+	for _, row := range rows {
+		if _, ok := done[row.Name]; !ok {
+			report.Existing = append(report.Existing, row.Name)
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,248 @@
+package lint
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/dimonk33/sql-migrator/pkg/gomigrator"
+)
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+
+	FormatText   = "text"
+	FormatJSON   = "json"
+	FormatGitHub = "github"
+)
+
+// Severity уровень найденной проблемы.
+type Severity = string
+
+// Finding проблема, найденная в миграции.
+type Finding struct {
+	File      string   `json:"file"`
+	Line      int      `json:"line"`
+	Rule      string   `json:"rule"`
+	Severity  Severity `json:"severity"`
+	Message   string   `json:"message"`
+	Statement string   `json:"statement,omitempty"`
+}
+
+// Config настройка набора правил: отключенные правила и переопределение уровня.
+type Config struct {
+	Disabled []string
+	Severity map[string]Severity
+}
+
+type Linter struct {
+	rules []Rule
+}
+
+var ErrFindings = errors.New("найдены проблемы в миграциях")
+
+func New(cfg Config) (*Linter, error) {
+	known := make(map[string]Rule, len(defaultRules))
+	for _, r := range defaultRules {
+		known[r.ID] = r
+	}
+
+	for _, id := range cfg.Disabled {
+		if _, ok := known[id]; !ok {
+			return nil, fmt.Errorf("неизвестное правило: %s", id)
+		}
+		delete(known, id)
+	}
+
+	for id, sev := range cfg.Severity {
+		r, ok := known[id]
+		if !ok {
+			if _, exists := ruleByID(id); exists {
+				continue
+			}
+			return nil, fmt.Errorf("неизвестное правило: %s", id)
+		}
+		if sev != SeverityError && sev != SeverityWarning {
+			return nil, fmt.Errorf("неизвестный уровень %s для правила %s", sev, id)
+		}
+		r.Severity = sev
+		known[id] = r
+	}
+
+	l := &Linter{rules: make([]Rule, 0, len(known))}
+	for _, r := range defaultRules {
+		if rule, ok := known[r.ID]; ok {
+			l.rules = append(l.rules, rule)
+		}
+	}
+
+	return l, nil
+}
+
+func (l *Linter) Rules() []Rule {
+	return l.rules
+}
+
+// LintMigrator проверяет непримененные sql миграции мигратора m.
+// Без подключения к базе проверяются все миграции каталога.
+func (l *Linter) LintMigrator(m *gomigrator.Migrator) ([]Finding, error) {
+	plan, err := m.Plan()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]Finding, 0)
+	for _, item := range plan {
+		list, err := l.LintFile(item.Path)
+		if err != nil {
+			return nil, fmt.Errorf("миграция %s: %w", item.Name, err)
+		}
+		out = append(out, list...)
+	}
+
+	return out, nil
+}
+
+// LintFile проверяет sql миграцию. Миграции других типов пропускаются.
+func (l *Linter) LintFile(path string) ([]Finding, error) {
+	if strings.Trim(filepath.Ext(path), ".") != gomigrator.SQLMigration {
+		return nil, nil
+	}
+
+	fileContent, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка открытия файла: %w", err)
+	}
+
+	return l.LintContent(path, string(fileContent))
+}
+
+func (l *Linter) LintContent(path string, content string) ([]Finding, error) {
+	out := make([]Finding, 0)
+
+	hasDown := true
+	down, err := gomigrator.ParseSQL(path, content, gomigrator.DownDirection)
+	if err != nil || len(down.Statements) == 0 {
+		if err != nil && !errors.Is(err, gomigrator.ErrWrongFileFormat) {
+			return nil, err
+		}
+		hasDown = false
+
+		// Без Down части собственный формат не разбирается, достраиваем ее для проверки Up
+		if strings.Contains(content, gomigrator.SQLUpPartID) && !strings.Contains(content, gomigrator.SQLDownPartID) {
+			content += "\n" + gomigrator.SQLDownPartID + "\n"
+		}
+	}
+
+	up, err := gomigrator.ParseSQL(path, content, gomigrator.UpDirection)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка парсинга файла: %w", err)
+	}
+
+	m := &migration{
+		created: make(map[string]struct{}),
+		hasDown: hasDown,
+	}
+
+	offset := 0
+	for _, stmt := range up.Statements {
+		line := 0
+		line, offset = locate(content, stmt, offset)
+		m.statements = append(m.statements, statement{text: stmt, norm: normalize(stmt), line: line})
+	}
+
+	for _, st := range m.statements {
+		if name, ok := createdTable(st.norm); ok {
+			m.created[name] = struct{}{}
+		}
+	}
+
+	for _, r := range l.rules {
+		for _, f := range r.check(m) {
+			f.File = path
+			f.Rule = r.ID
+			f.Severity = r.Severity
+			out = append(out, f)
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Line < out[j].Line
+	})
+
+	return out, nil
+}
+
+// HasErrors есть ли среди проблем проблемы уровня не ниже failOn.
+func HasErrors(list []Finding, failOn Severity) bool {
+	for _, f := range list {
+		if f.Severity == SeverityError || failOn == SeverityWarning {
+			return true
+		}
+	}
+
+	return false
+}
+
+func Write(w io.Writer, list []Finding, format string) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(list)
+	case FormatGitHub:
+		for _, f := range list {
+			level := "error"
+			if f.Severity == SeverityWarning {
+				level = "warning"
+			}
+			if _, err := fmt.Fprintf(w, "::%s file=%s,line=%d,title=%s::%s\n", level, f.File, f.Line, f.Rule, f.Message); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatText:
+		for _, f := range list {
+			if _, err := fmt.Fprintf(w, "%s:%d: %s [%s] %s\n", f.File, f.Line, f.Severity, f.Rule, f.Message); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("неизвестный формат вывода: %s", format)
+}
+
+var spaceRe = regexp.MustCompile(`\s+`)
+
+// Приведение запроса к верхнему регистру с одиночными пробелами, без кавычек у идентификаторов.
+func normalize(stmt string) string {
+	return strings.TrimSpace(spaceRe.ReplaceAllString(strings.ToUpper(strings.ReplaceAll(stmt, `"`, "")), " "))
+}
+
+// locate определяет строку начала запроса в файле, поиск идет после предыдущего запроса.
+// Разборщик убирает комментарии внутри запроса, поэтому ищется самый длинный найденный
+// префикс первой строки.
+func locate(content string, stmt string, from int) (int, int) {
+	stmt = strings.TrimSpace(stmt)
+	if nl := strings.IndexByte(stmt, '\n'); nl != -1 {
+		stmt = stmt[:nl]
+	}
+
+	const minPrefix = 6
+	for n := len(stmt); n >= minPrefix || n == len(stmt) && n > 0; n-- {
+		if i := strings.Index(content[from:], stmt[:n]); i != -1 {
+			pos := from + i
+			return strings.Count(content[:pos], "\n") + 1, pos + n
+		}
+	}
+
+	return 0, from
+}
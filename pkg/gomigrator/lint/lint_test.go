@@ -0,0 +1,137 @@
+package lint
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testDangerousContent = `-- ===gm Up===
+CREATE TABLE orders (id int);
+CREATE INDEX orders_id_idx ON orders (id);
+CREATE INDEX users_name_idx ON users (name);
+CREATE INDEX CONCURRENTLY users_email_idx ON users (email);
+ALTER TABLE users ADD COLUMN token uuid DEFAULT gen_random_uuid();
+ALTER TABLE users ADD COLUMN active bool DEFAULT true;
+ALTER TABLE users ADD COLUMN num bigserial;
+ALTER TABLE users ALTER COLUMN name SET NOT NULL;
+ALTER TABLE users DROP COLUMN legacy;
+ALTER TABLE users DROP CONSTRAINT users_fk;
+ALTER TABLE users ALTER COLUMN age TYPE bigint;
+DROP TABLE sessions;
+
+-- ===gm Down===
+DROP TABLE orders;
+`
+
+func rulesOf(list []Finding) []string {
+	out := make([]string, 0, len(list))
+	for _, f := range list {
+		out = append(out, f.Rule)
+	}
+	return out
+}
+
+func TestLinter_LintContent(t *testing.T) {
+	l, err := New(Config{})
+	require.NoError(t, err)
+
+	list, err := l.LintContent("00001_users.sql", testDangerousContent)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		RuleCreateIndexConcurrently,
+		RuleVolatileDefault,
+		RuleVolatileDefault,
+		RuleSetNotNull,
+		RuleDropColumn,
+		RuleAlterColumnType,
+		RuleDropTable,
+	}, rulesOf(list))
+
+	require.Equal(t, 4, list[0].Line)
+	require.Equal(t, 6, list[1].Line)
+	require.Equal(t, 8, list[2].Line)
+	require.Equal(t, 13, list[6].Line)
+	require.Equal(t, SeverityWarning, list[4].Severity)
+	require.Equal(t, "00001_users.sql", list[0].File)
+}
+
+func TestLinter_MissingDown(t *testing.T) {
+	l, err := New(Config{})
+	require.NoError(t, err)
+
+	list, err := l.LintContent("00001_users.sql", "-- ===gm Up===\nCREATE TABLE t (id int);\n")
+	require.NoError(t, err)
+	require.Equal(t, []string{RuleMissingDown}, rulesOf(list))
+	require.Equal(t, 2, list[0].Line)
+
+	list, err = l.LintContent("00001_users.sql", "-- +goose Up\nCREATE TABLE t (id int);\n-- +goose Down\n")
+	require.NoError(t, err)
+	require.Equal(t, []string{RuleMissingDown}, rulesOf(list))
+}
+
+func TestLinter_MultiLine(t *testing.T) {
+	l, err := New(Config{})
+	require.NoError(t, err)
+
+	content := `-- ===gm Up===
+CREATE INDEX users_name_idx
+ON users (name);
+-- комментарий
+ALTER TABLE users
+    ALTER COLUMN name SET NOT NULL;
+
+-- ===gm Down===
+DROP INDEX users_name_idx;
+`
+
+	list, err := l.LintContent("00001_users.sql", content)
+	require.NoError(t, err)
+	require.Equal(t, []string{RuleCreateIndexConcurrently, RuleSetNotNull}, rulesOf(list))
+	require.Equal(t, 2, list[0].Line)
+	require.Equal(t, 5, list[1].Line)
+	require.Equal(t, "CREATE INDEX users_name_idx\nON users (name)", list[0].Statement)
+}
+
+func TestLinter_Config(t *testing.T) {
+	l, err := New(Config{
+		Disabled: []string{RuleDropTable, RuleDropColumn},
+		Severity: map[string]Severity{RuleCreateIndexConcurrently: SeverityWarning},
+	})
+	require.NoError(t, err)
+
+	list, err := l.LintContent("00001_users.sql", testDangerousContent)
+	require.NoError(t, err)
+	require.NotContains(t, rulesOf(list), RuleDropTable)
+	require.NotContains(t, rulesOf(list), RuleDropColumn)
+	require.Equal(t, SeverityWarning, list[0].Severity)
+
+	_, err = New(Config{Disabled: []string{"unknown"}})
+	require.Error(t, err)
+
+	_, err = New(Config{Severity: map[string]Severity{RuleDropTable: "fatal"}})
+	require.Error(t, err)
+}
+
+func TestHasErrors(t *testing.T) {
+	warn := []Finding{{Severity: SeverityWarning}}
+	require.False(t, HasErrors(warn, SeverityError))
+	require.True(t, HasErrors(warn, SeverityWarning))
+	require.True(t, HasErrors([]Finding{{Severity: SeverityError}}, SeverityError))
+	require.False(t, HasErrors(nil, SeverityWarning))
+}
+
+func TestWrite(t *testing.T) {
+	list := []Finding{{File: "a.sql", Line: 3, Rule: RuleDropTable, Severity: SeverityWarning, Message: "msg"}}
+
+	buf := bytes.Buffer{}
+	require.NoError(t, Write(&buf, list, FormatGitHub))
+	require.Equal(t, "::warning file=a.sql,line=3,title=drop-table::msg\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, Write(&buf, list, FormatText))
+	require.Equal(t, "a.sql:3: warning [drop-table] msg\n", buf.String())
+
+	require.Error(t, Write(&buf, list, "xml"))
+}
@@ -0,0 +1,254 @@
+package lint
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	RuleCreateIndexConcurrently = "create-index-concurrently"
+	RuleVolatileDefault         = "volatile-default"
+	RuleSetNotNull              = "set-not-null"
+	RuleDropColumn              = "drop-column"
+	RuleDropTable               = "drop-table"
+	RuleAlterColumnType         = "alter-column-type"
+	RuleMissingDown             = "missing-down"
+)
+
+// Rule правило проверки миграции.
+type Rule struct {
+	ID          string
+	Severity    Severity
+	Description string
+	check       func(m *migration) []Finding
+}
+
+type statement struct {
+	text string
+	norm string
+	line int
+}
+
+type migration struct {
+	statements []statement
+	created    map[string]struct{}
+	hasDown    bool
+}
+
+var defaultRules = []Rule{
+	{
+		ID:          RuleCreateIndexConcurrently,
+		Severity:    SeverityError,
+		Description: "создание индекса без CONCURRENTLY блокирует запись в таблицу",
+		check:       checkCreateIndex,
+	},
+	{
+		ID:          RuleVolatileDefault,
+		Severity:    SeverityError,
+		Description: "добавление столбца с изменчивым значением по умолчанию перезаписывает таблицу",
+		check:       checkVolatileDefault,
+	},
+	{
+		ID:          RuleSetNotNull,
+		Severity:    SeverityError,
+		Description: "SET NOT NULL проверяет всю таблицу под эксклюзивной блокировкой",
+		check:       checkSetNotNull,
+	},
+	{
+		ID:          RuleDropColumn,
+		Severity:    SeverityWarning,
+		Description: "удаление столбца ломает код, который его еще использует",
+		check:       checkDropColumn,
+	},
+	{
+		ID:          RuleDropTable,
+		Severity:    SeverityWarning,
+		Description: "удаление таблицы необратимо",
+		check:       checkDropTable,
+	},
+	{
+		ID:          RuleAlterColumnType,
+		Severity:    SeverityError,
+		Description: "смена типа столбца может перезаписать таблицу",
+		check:       checkAlterColumnType,
+	},
+	{
+		ID:          RuleMissingDown,
+		Severity:    SeverityWarning,
+		Description: "у миграции нет Down части",
+		check:       checkMissingDown,
+	},
+}
+
+// AllRules возвращает все правила с уровнями по умолчанию.
+func AllRules() []Rule {
+	return append([]Rule(nil), defaultRules...)
+}
+
+func ruleByID(id string) (Rule, bool) {
+	for _, r := range defaultRules {
+		if r.ID == id {
+			return r, true
+		}
+	}
+
+	return Rule{}, false
+}
+
+const identPattern = `((?:[A-Z0-9_$]+\.)?[A-Z0-9_$]+)`
+
+var (
+	createTableRe  = regexp.MustCompile(`^CREATE (?:UNLOGGED |TEMP |TEMPORARY )?TABLE (?:IF NOT EXISTS )?` + identPattern)
+	createIndexRe  = regexp.MustCompile(`^CREATE (?:UNIQUE )?INDEX (CONCURRENTLY )?(?:IF NOT EXISTS )?(?:[A-Z0-9_$.]+ )?ON (?:ONLY )?` + identPattern)
+	alterTableRe   = regexp.MustCompile(`^ALTER TABLE (?:IF EXISTS )?(?:ONLY )?` + identPattern)
+	addColumnRe    = regexp.MustCompile(`ADD (?:COLUMN )?(?:IF NOT EXISTS )?[A-Z0-9_$]+ ([^,]*)`)
+	volatileRe     = regexp.MustCompile(`DEFAULT [^,]*\b(RANDOM|CLOCK_TIMESTAMP|TIMEOFDAY|GEN_RANDOM_UUID|UUID_GENERATE_V[0-9A-Z_]*|NEXTVAL)\s*\(`)
+	serialTypeRe   = regexp.MustCompile(`^(SMALLSERIAL|SERIAL|BIGSERIAL|SERIAL2|SERIAL4|SERIAL8)\b`)
+	setNotNullRe   = regexp.MustCompile(`ALTER (?:COLUMN )?[A-Z0-9_$]+ SET NOT NULL`)
+	dropColumnRe   = regexp.MustCompile(`DROP (?:COLUMN )?(?:IF EXISTS )?([A-Z0-9_$]+)`)
+	dropTableRe    = regexp.MustCompile(`^DROP TABLE (?:IF EXISTS )?` + identPattern)
+	alterColTypeRe = regexp.MustCompile(`ALTER (?:COLUMN )?[A-Z0-9_$]+ (?:SET DATA )?TYPE `)
+)
+
+func createdTable(norm string) (string, bool) {
+	if m := createTableRe.FindStringSubmatch(norm); m != nil {
+		return tableName(m[1]), true
+	}
+
+	return "", false
+}
+
+// Имя таблицы без схемы public, чтобы t и public.t совпадали.
+func tableName(name string) string {
+	return strings.TrimPrefix(name, "PUBLIC.")
+}
+
+func (m *migration) isCreated(name string) bool {
+	_, ok := m.created[tableName(name)]
+	return ok
+}
+
+// alterTable возвращает таблицу, если запрос изменяет уже существующую таблицу.
+func (m *migration) alterTable(st statement) (string, bool) {
+	match := alterTableRe.FindStringSubmatch(st.norm)
+	if match == nil || m.isCreated(match[1]) {
+		return "", false
+	}
+
+	return match[1], true
+}
+
+func finding(st statement, msg string) Finding {
+	return Finding{Line: st.line, Message: msg, Statement: st.text}
+}
+
+func checkCreateIndex(m *migration) []Finding {
+	out := make([]Finding, 0)
+	for _, st := range m.statements {
+		match := createIndexRe.FindStringSubmatch(st.norm)
+		if match == nil || match[1] != "" || m.isCreated(match[2]) {
+			continue
+		}
+		out = append(out, finding(st, "индекс на таблице "+strings.ToLower(match[2])+
+			" создается без CONCURRENTLY, запись в таблицу будет заблокирована"))
+	}
+
+	return out
+}
+
+func checkVolatileDefault(m *migration) []Finding {
+	out := make([]Finding, 0)
+	for _, st := range m.statements {
+		table, ok := m.alterTable(st)
+		if !ok {
+			continue
+		}
+		for _, add := range addColumnRe.FindAllStringSubmatch(st.norm, -1) {
+			if fn := volatileRe.FindStringSubmatch(add[0]); fn != nil {
+				out = append(out, finding(st, "столбец в таблице "+strings.ToLower(table)+
+					" добавляется со значением по умолчанию "+strings.ToLower(fn[1])+"(), таблица будет перезаписана"))
+				continue
+			}
+			if serialTypeRe.MatchString(add[1]) {
+				out = append(out, finding(st, "столбец serial в таблице "+strings.ToLower(table)+
+					" заполняется из последовательности, таблица будет перезаписана"))
+			}
+		}
+	}
+
+	return out
+}
+
+func checkSetNotNull(m *migration) []Finding {
+	out := make([]Finding, 0)
+	for _, st := range m.statements {
+		table, ok := m.alterTable(st)
+		if !ok || !setNotNullRe.MatchString(st.norm) {
+			continue
+		}
+		out = append(out, finding(st, "SET NOT NULL на таблице "+strings.ToLower(table)+
+			" сканирует таблицу под блокировкой, сначала добавьте CHECK (...) NOT VALID"))
+	}
+
+	return out
+}
+
+func checkDropColumn(m *migration) []Finding {
+	out := make([]Finding, 0)
+	for _, st := range m.statements {
+		table, ok := m.alterTable(st)
+		if !ok {
+			continue
+		}
+		for _, drop := range dropColumnRe.FindAllStringSubmatch(st.norm, -1) {
+			// DROP CONSTRAINT, DROP DEFAULT и подобные не удаляют столбец
+			if drop[1] == "CONSTRAINT" || drop[1] == "DEFAULT" || drop[1] == "NOT" || drop[1] == "IDENTITY" || drop[1] == "EXPRESSION" {
+				continue
+			}
+			out = append(out, finding(st, "удаление столбца "+strings.ToLower(drop[1])+
+				" из таблицы "+strings.ToLower(table)))
+		}
+	}
+
+	return out
+}
+
+func checkDropTable(m *migration) []Finding {
+	out := make([]Finding, 0)
+	for _, st := range m.statements {
+		match := dropTableRe.FindStringSubmatch(st.norm)
+		if match == nil || m.isCreated(match[1]) {
+			continue
+		}
+		out = append(out, finding(st, "удаление таблицы "+strings.ToLower(match[1])))
+	}
+
+	return out
+}
+
+func checkAlterColumnType(m *migration) []Finding {
+	out := make([]Finding, 0)
+	for _, st := range m.statements {
+		table, ok := m.alterTable(st)
+		if !ok || !alterColTypeRe.MatchString(st.norm) {
+			continue
+		}
+		out = append(out, finding(st, "смена типа столбца в таблице "+strings.ToLower(table)+
+			" может перезаписать таблицу под эксклюзивной блокировкой"))
+	}
+
+	return out
+}
+
+func checkMissingDown(m *migration) []Finding {
+	if m.hasDown {
+		return nil
+	}
+
+	line := 0
+	if len(m.statements) > 0 {
+		line = m.statements[0].line
+	}
+
+	return []Finding{{Line: line, Message: "нет Down части, миграцию нельзя откатить"}}
+}
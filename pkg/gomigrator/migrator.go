@@ -18,6 +18,7 @@ import (
 const (
 	SQLMigration MigrateType = "sql"
 	GoMigration  MigrateType = "go"
+	ShMigration  MigrateType = "sh"
 
 	opTimeout = 60 * time.Minute
 )
@@ -25,7 +26,7 @@ const (
 type MigrateType = string
 
 func Validate(mt MigrateType) error {
-	if mt != SQLMigration && mt != GoMigration {
+	if mt != SQLMigration && mt != GoMigration && mt != ShMigration {
 		return errors.New("неизвестный тип миграции")
 	}
 
@@ -33,16 +34,29 @@ func Validate(mt MigrateType) error {
 }
 
 type Migrator struct {
-	logger  Logger
-	dirPath string
-	db      DB
-	finder  *migfile.Finder
+	logger     Logger
+	dirPath    string
+	db         DB
+	dbConn     *DBConnParam
+	dbReadOnly bool
+	finder     *migfile.Finder
+	outOfOrder OutOfOrderPolicy
+	versioning string
+	layout     string
+	goCacheDir string
+	goLogLevel string
+	goVars     map[string]string
+	goTimeout  time.Duration
+	goBin      string
+	goRoot     string
 }
 
 type DBConnParam = migdb.ConnParam
 
 type MigrateStatus = migdb.MigrateInfo
 
+type RepeatableStatus = migdb.RepeatableInfo
+
 type Logger interface {
 	Info(v ...any)
 	Error(v ...any)
@@ -53,11 +67,20 @@ type Logger interface {
 type DB interface {
 	executer.DBSQL
 	executer.DBGo
+	executer.DBSh
 	Lock(ctx context.Context, sign string) bool
 	Unlock(ctx context.Context, sign string) bool
 	Find(ctx context.Context, name string) (int, error)
 	FindLast(ctx context.Context) (string, error)
 	FindAllApplied(ctx context.Context) ([]migdb.MigrateInfo, error)
+	FindAllRepeatable(ctx context.Context) ([]migdb.RepeatableInfo, error)
+	HistoryExists(ctx context.Context) (bool, error)
+	Init(ctx context.Context) error
+	ReadHistory(ctx context.Context, source string, table string) ([]migdb.HistoryRecord, error)
+	ImportApplied(ctx context.Context, list []migdb.MigrateInfo) ([]string, error)
+	VerifyTx(ctx context.Context, items []migdb.VerifyItem) ([]migdb.VerifyResult, error)
+	Snapshot(ctx context.Context) (migdb.Catalog, error)
+	DesiredSnapshot(ctx context.Context, sqlPool []string) (migdb.Catalog, migdb.Catalog, error)
 }
 
 type MigrateExec interface {
@@ -65,35 +88,100 @@ type MigrateExec interface {
 	DownExec(ctx context.Context, path string) error
 }
 
-var ErrNoMigrations = errors.New("отсутствуют миграции для применения")
+var (
+	ErrNoMigrations = errors.New("отсутствуют миграции для применения")
+	ErrOffline      = errors.New("операция требует подключения к базе данных")
+
+	ErrRepeatableLocked = errors.New("повторяемая миграция применяется другим процессом")
+)
 
+// New создает мигратор, подключение к базе открывается при первой операции, которой оно нужно.
 func New(l Logger, dir string, dbConn *DBConnParam) (*Migrator, error) {
 	m := &Migrator{
-		logger:  l,
-		dirPath: dir,
+		logger:     l,
+		dirPath:    dir,
+		dbConn:     dbConn,
+		outOfOrder: OutOfOrderError,
+		versioning: VersionTimestamp,
+		layout:     LayoutSingle,
+		goCacheDir: executer.DefaultGoCacheDir(),
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
-	defer cancel()
+	var err error
 
-	db, err := migdb.NewPgMigrator(ctx, dbConn, l)
+	m.finder, err = migfile.NewFileFinder()
 	if err != nil {
 		return nil, err
 	}
+
+	return m, nil
+}
+
+// NewOffline создает мигратор для операций только с файлами миграций, без базы данных.
+func NewOffline(l Logger, dir string) (*Migrator, error) {
+	return New(l, dir, nil)
+}
+
+func (m *Migrator) IsOffline() bool {
+	return m.db == nil && m.dbConn == nil
+}
+
+func (m *Migrator) connect(ctx context.Context) error {
+	if m.db != nil {
+		if !m.dbReadOnly {
+			return nil
+		}
+
+		if err := m.db.Init(ctx); err != nil {
+			return fmt.Errorf("подключение к базе данных: %w", err)
+		}
+		m.dbReadOnly = false
+
+		return nil
+	}
+
+	if m.dbConn == nil {
+		return ErrOffline
+	}
+
+	db, err := migdb.NewPgMigrator(ctx, m.dbConn, m.logger)
+	if err != nil {
+		return fmt.Errorf("подключение к базе данных: %w", err)
+	}
 	m.db = db
 
-	m.finder, err = migfile.NewFileFinder()
+	return nil
+}
+
+// connectReadOnly подключение для операций, которые только читают базу:
+// служебные таблицы не создаются.
+func (m *Migrator) connectReadOnly(ctx context.Context) error {
+	if m.db != nil {
+		return nil
+	}
+
+	if m.dbConn == nil {
+		return ErrOffline
+	}
+
+	db, err := migdb.NewPgReader(ctx, m.dbConn, m.logger)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("подключение к базе данных: %w", err)
 	}
+	m.db = db
+	m.dbReadOnly = true
 
-	return m, nil
+	return nil
 }
 
 func (m *Migrator) Status() ([]MigrateStatus, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
 	defer cancel()
 
+	if err := m.connect(ctx); err != nil {
+		return nil, err
+	}
+
 	list, err := m.db.FindAllApplied(ctx)
 	if err != nil {
 		return nil, err
@@ -102,9 +190,30 @@ func (m *Migrator) Status() ([]MigrateStatus, error) {
 	return list, nil
 }
 
+func (m *Migrator) RepeatableStatus() ([]RepeatableStatus, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+	defer cancel()
+
+	if err := m.connect(ctx); err != nil {
+		return nil, err
+	}
+
+	list, err := m.db.FindAllRepeatable(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
 func (m *Migrator) Version() (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
 	defer cancel()
+
+	if err := m.connect(ctx); err != nil {
+		return "", err
+	}
+
 	v, err := m.db.FindLast(ctx)
 	if err != nil {
 		return "", err
@@ -118,7 +227,7 @@ func (m *Migrator) Create(migrateName string, migrateType MigrateType) (string,
 		return "", err
 	}
 
-	if migrateType != migfile.SQLFile && migrateType != migfile.GoFile {
+	if migrateType != migfile.SQLFile && migrateType != migfile.GoFile && migrateType != migfile.ShFile {
 		return "", errors.New("неверный тип миграции: " + migrateType)
 	}
 
@@ -128,6 +237,13 @@ func (m *Migrator) Create(migrateName string, migrateType MigrateType) (string,
 	}
 
 	t := migfile.NewTemplate(m.logger, m.dirPath)
+	if err = t.SetVersioning(m.versioning); err != nil {
+		return "", err
+	}
+
+	if err = t.SetLayout(m.layout); err != nil {
+		return "", err
+	}
 
 	var fname string
 	if fname, err = t.Create(migrateName, migrateType); err != nil {
@@ -141,43 +257,41 @@ func (m *Migrator) Up() error {
 	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
 	defer cancel()
 
-	flist, err := m.finder.ScanDir(ctx, m.dirPath)
+	if err := m.connect(ctx); err != nil {
+		return err
+	}
+
+	mlist, rlist, err := m.scanDir(ctx)
 	if err != nil {
-		return fmt.Errorf("ошибка поиска миграций в каталоге: %w", err)
+		return err
 	}
-	m.logger.Info("Cписок миграций:\n", flist)
 
-	appliedMigrations, err := m.db.FindAllApplied(ctx)
+	pending, outOfOrder, err := m.pending(ctx, mlist)
 	if err != nil {
-		return fmt.Errorf("ошибка получения миграций из базы: %w", err)
+		return err
 	}
 
-	for _, am := range appliedMigrations {
-		_, ok := flist[am.Name]
-		if ok {
-			delete(flist, am.Name)
-		}
+	if err = m.checkOutOfOrder(outOfOrder); err != nil {
+		return err
 	}
 
-	m.logger.Info("Cписок миграций для применения:\n", flist)
+	m.logger.Info("Cписок миграций для применения:\n", pending)
 
-	if len(flist) == 0 {
-		return ErrNoMigrations
+	changed, err := m.changedRepeatable(ctx, rlist)
+	if err != nil {
+		return err
 	}
 
-	keys := make([]string, 0, len(flist))
+	m.logger.Info("Cписок повторяемых миграций для применения:\n", sortedKeys(changed))
 
-	for key := range flist {
-		keys = append(keys, key)
+	if len(pending) == 0 && len(changed) == 0 {
+		return ErrNoMigrations
 	}
-	sort.SliceStable(keys, func(i, j int) bool {
-		return flist[keys[i]] < flist[keys[j]]
-	})
 
 	var mExecuter MigrateExec
-	for _, k := range keys {
-		f := flist[k]
-		m.logger.Info("Применение миграции", k)
+	for _, mg := range pending {
+		f := mg.Path
+		m.logger.Info("Применение миграции", mg.Name)
 
 		dbSign := filepath.Base(f)
 		if !m.db.Lock(ctx, dbSign) {
@@ -185,16 +299,10 @@ func (m *Migrator) Up() error {
 			continue
 		}
 
-		ext := strings.Trim(filepath.Ext(f), ".")
-
-		switch ext {
-		case migfile.SQLFile:
-			mExecuter = executer.NewSQLMigrate(m.db)
-		case migfile.GoFile:
-			mExecuter = executer.NewGoMigrate(m.db, m.logger)
+		mExecuter, err = m.newExecuter(f)
+		if err == nil {
+			err = mExecuter.UpExec(ctx, f)
 		}
-
-		err = mExecuter.UpExec(ctx, f)
 		if err != nil {
 			if !m.db.Unlock(ctx, dbSign) {
 				m.logger.Error("ошибка разблокировки миграции ", dbSign)
@@ -210,13 +318,90 @@ func (m *Migrator) Up() error {
 		m.logger.Info("Миграция", f, "применена")
 	}
 
+	return m.upRepeatable(ctx, changed)
+}
+
+// repeatableFile измененная повторяемая миграция и контрольная сумма, по которой
+// изменение обнаружено: она же записывается в историю при применении.
+type repeatableFile struct {
+	Path     string
+	Checksum string
+}
+
+// Повторяемые миграции применяются после версионных, если их содержимое изменилось.
+// Миграция, заблокированная другим процессом, не пропускается молча: иначе up завершится
+// успешно, а изменение останется непримененным.
+func (m *Migrator) upRepeatable(ctx context.Context, rlist map[string]repeatableFile) error {
+	sqlExecuter := executer.NewSQLMigrate(m.db)
+	for _, k := range sortedKeys(rlist) {
+		f := rlist[k].Path
+		m.logger.Info("Применение повторяемой миграции", k)
+
+		if !m.db.Lock(ctx, k) {
+			return fmt.Errorf("%w: %s", ErrRepeatableLocked, k)
+		}
+
+		err := sqlExecuter.RepeatExec(ctx, f, rlist[k].Checksum)
+
+		if !m.db.Unlock(ctx, k) {
+			m.logger.Error("ошибка разблокировки миграции ", k)
+		}
+
+		if err != nil {
+			return fmt.Errorf("ошибка применения повторяемой миграции %s: %w", f, err)
+		}
+
+		m.logger.Info("Повторяемая миграция", f, "применена")
+	}
+
 	return nil
 }
 
+func sortedKeys[V any](rlist map[string]V) []string {
+	keys := make([]string, 0, len(rlist))
+
+	for key := range rlist {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func (m *Migrator) changedRepeatable(ctx context.Context, rlist map[string]string) (map[string]repeatableFile, error) {
+	applied, err := m.db.FindAllRepeatable(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения повторяемых миграций из базы: %w", err)
+	}
+
+	checksums := make(map[string]string, len(applied))
+	for _, am := range applied {
+		checksums[am.Name] = am.Checksum
+	}
+
+	changed := make(map[string]repeatableFile, len(rlist))
+	for name, path := range rlist {
+		checksum, err := migfile.Checksum(path)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка расчета контрольной суммы %s: %w", path, err)
+		}
+
+		if checksums[name] != checksum {
+			changed[name] = repeatableFile{Path: path, Checksum: checksum}
+		}
+	}
+
+	return changed, nil
+}
+
 func (m *Migrator) Down() error {
 	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
 	defer cancel()
 
+	if err := m.connect(ctx); err != nil {
+		return err
+	}
+
 	lastMigrationName, lastMigrationPath, err := m.getLastMigration(ctx)
 	if err != nil {
 		return err
@@ -235,13 +420,8 @@ func (m *Migrator) Down() error {
 	var mExecuter MigrateExec
 
 	m.logger.Info("Откат миграции", lastMigrationName)
-	ext := strings.Trim(filepath.Ext(lastMigrationName), ".")
-
-	switch ext {
-	case migfile.SQLFile:
-		mExecuter = executer.NewSQLMigrate(m.db)
-	case migfile.GoFile:
-		mExecuter = executer.NewGoMigrate(m.db, m.logger)
+	if mExecuter, err = m.newExecuter(lastMigrationPath); err != nil {
+		return err
 	}
 
 	err = mExecuter.DownExec(ctx, lastMigrationPath)
@@ -258,6 +438,10 @@ func (m *Migrator) Redo() error {
 	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
 	defer cancel()
 
+	if err := m.connect(ctx); err != nil {
+		return err
+	}
+
 	lastMigrationName, lastMigrationPath, err := m.getLastMigration(ctx)
 	if err != nil {
 		return err
@@ -276,13 +460,8 @@ func (m *Migrator) Redo() error {
 	var mExecuter MigrateExec
 
 	m.logger.Info("Откат миграции", lastMigrationName)
-	ext := strings.Trim(filepath.Ext(lastMigrationName), ".")
-
-	switch ext {
-	case migfile.SQLFile:
-		mExecuter = executer.NewSQLMigrate(m.db)
-	case migfile.GoFile:
-		mExecuter = executer.NewGoMigrate(m.db, m.logger)
+	if mExecuter, err = m.newExecuter(lastMigrationPath); err != nil {
+		return err
 	}
 
 	err = mExecuter.DownExec(ctx, lastMigrationPath)
@@ -300,12 +479,37 @@ func (m *Migrator) Redo() error {
 	return nil
 }
 
+func migrateType(path string) MigrateType {
+	if migfile.IsGoDir(path) {
+		return GoMigration
+	}
+
+	return strings.Trim(filepath.Ext(path), ".")
+}
+
+func (m *Migrator) newExecuter(path string) (MigrateExec, error) {
+	switch migrateType(path) {
+	case migfile.SQLFile:
+		return executer.NewSQLMigrate(m.db), nil
+	case migfile.GoFile:
+		gm := executer.NewGoMigrate(m.db, m.logger)
+		gm.SetCacheDir(m.goCacheDir)
+		gm.SetEnv(m.goLogLevel, m.goVars)
+		gm.SetTimeout(m.goTimeout)
+		gm.SetToolchain(m.goBin, m.goRoot)
+		return gm, nil
+	case migfile.ShFile:
+		return executer.NewShMigrate(m.db, m.logger), nil
+	}
+
+	return nil, fmt.Errorf("неизвестный тип миграции: %s", path)
+}
+
 func (m *Migrator) getLastMigration(ctx context.Context) (string, string, error) {
-	flist, err := m.finder.ScanDir(ctx, m.dirPath)
+	mlist, _, err := m.scanDir(ctx)
 	if err != nil {
-		return "", "", fmt.Errorf("ошибка поиска миграций в каталоге: %w", err)
+		return "", "", err
 	}
-	m.logger.Info("Cписок миграций:\n", flist)
 
 	var lastMigrationName string
 
@@ -313,10 +517,32 @@ func (m *Migrator) getLastMigration(ctx context.Context) (string, string, error)
 		return "", "", fmt.Errorf("ошибка получения последней миграции из базы: %w", err)
 	}
 
-	path, ok := flist[lastMigrationName]
-	if !ok {
-		return "", "", fmt.Errorf("миграция %s отсутствует на диске", lastMigrationName)
+	for _, mg := range mlist {
+		if mg.Name == lastMigrationName {
+			return lastMigrationName, mg.Path, nil
+		}
+	}
+
+	return "", "", fmt.Errorf("миграция %s отсутствует на диске", lastMigrationName)
+}
+
+// Поиск миграций в каталоге: версионные упорядочены по номеру версии.
+func (m *Migrator) scanDir(ctx context.Context) ([]migfile.Migration, map[string]string, error) {
+	flist, err := m.finder.ScanDir(ctx, m.dirPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка поиска миграций в каталоге: %w", err)
+	}
+	m.logger.Info("Cписок миграций:\n", flist)
+
+	flist, rlist, err := m.finder.SplitRepeatable(flist)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка определения повторяемых миграций: %w", err)
+	}
+
+	mlist, err := m.finder.Versioned(flist)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка разбора версий миграций: %w", err)
 	}
 
-	return lastMigrationName, path, nil
+	return mlist, rlist, nil
 }
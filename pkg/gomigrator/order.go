@@ -0,0 +1,147 @@
+package gomigrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	migfile "github.com/dimonk33/sql-migrator/internal/file"
+)
+
+const (
+	OutOfOrderError OutOfOrderPolicy = "error"
+	OutOfOrderWarn  OutOfOrderPolicy = "warn"
+	OutOfOrderAllow OutOfOrderPolicy = "allow"
+)
+
+// OutOfOrderPolicy поведение при наличии непримененных миграций
+// с версией ниже последней примененной.
+type OutOfOrderPolicy = string
+
+type Migration = migfile.Migration
+
+var ErrOutOfOrder = errors.New("найдены миграции с версией ниже последней примененной")
+
+func ValidateOutOfOrder(p OutOfOrderPolicy) error {
+	switch p {
+	case OutOfOrderError, OutOfOrderWarn, OutOfOrderAllow:
+		return nil
+	}
+
+	return fmt.Errorf("неизвестная политика применения миграций вне очереди: %s", p)
+}
+
+func (m *Migrator) SetOutOfOrderPolicy(p OutOfOrderPolicy) error {
+	if err := ValidateOutOfOrder(p); err != nil {
+		return err
+	}
+
+	m.outOfOrder = p
+
+	return nil
+}
+
+// OutOfOrder возвращает непримененные миграции, версия которых ниже последней примененной.
+func (m *Migrator) OutOfOrder() ([]Migration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+	defer cancel()
+
+	if err := m.connect(ctx); err != nil {
+		return nil, err
+	}
+
+	mlist, _, err := m.scanDir(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, outOfOrder, err := m.pending(ctx, mlist)
+	if err != nil {
+		return nil, err
+	}
+
+	return outOfOrder, nil
+}
+
+// Validate проверяет каталог миграций и состояние базы согласно политике вне очереди.
+func (m *Migrator) Validate() error {
+	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+	defer cancel()
+
+	if err := m.connect(ctx); err != nil {
+		return err
+	}
+
+	mlist, _, err := m.scanDir(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, outOfOrder, err := m.pending(ctx, mlist)
+	if err != nil {
+		return err
+	}
+
+	return m.checkOutOfOrder(outOfOrder)
+}
+
+// Разделение миграций каталога на ожидающие применения и примененные вне очереди.
+func (m *Migrator) pending(
+	ctx context.Context,
+	mlist []migfile.Migration,
+) ([]migfile.Migration, []migfile.Migration, error) {
+	appliedMigrations, err := m.db.FindAllApplied(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка получения миграций из базы: %w", err)
+	}
+
+	applied := make(map[string]struct{}, len(appliedMigrations))
+	for _, am := range appliedMigrations {
+		applied[am.Name] = struct{}{}
+	}
+
+	var last migfile.Version
+	if len(appliedMigrations) > 0 {
+		if last, err = migfile.ParseVersion(appliedMigrations[0].Name); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	pending := make([]migfile.Migration, 0, len(mlist))
+	outOfOrder := make([]migfile.Migration, 0)
+	for _, mg := range mlist {
+		if _, ok := applied[mg.Name]; ok {
+			continue
+		}
+
+		pending = append(pending, mg)
+
+		if len(appliedMigrations) > 0 && mg.Version.Compare(last) < 0 {
+			outOfOrder = append(outOfOrder, mg)
+		}
+	}
+
+	return pending, outOfOrder, nil
+}
+
+func (m *Migrator) checkOutOfOrder(outOfOrder []migfile.Migration) error {
+	if len(outOfOrder) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(outOfOrder))
+	for _, mg := range outOfOrder {
+		names = append(names, mg.Name)
+	}
+
+	switch m.outOfOrder {
+	case OutOfOrderAllow:
+		return nil
+	case OutOfOrderWarn:
+		m.logger.Warning(ErrOutOfOrder.Error()+":", strings.Join(names, ", "))
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s", ErrOutOfOrder, strings.Join(names, ", "))
+}
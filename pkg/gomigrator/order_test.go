@@ -0,0 +1,244 @@
+package gomigrator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	migdb "github.com/dimonk33/sql-migrator/internal/db"
+	migfile "github.com/dimonk33/sql-migrator/internal/file"
+	"github.com/stretchr/testify/require"
+)
+
+const testSQLContent = migfile.SQLUpPartID + "\nSELECT 1;\n" + migfile.SQLDownPartID + "\nSELECT 2;\n"
+
+// testDB база с историей миграций в памяти, остальные методы DB не используются.
+type testDB struct {
+	DB
+	applied    []string
+	repeatable []migdb.RepeatableInfo
+	noHistory  bool
+	inits      int
+}
+
+func (db *testDB) Init(_ context.Context) error {
+	db.inits++
+	return nil
+}
+
+func (db *testDB) HistoryExists(_ context.Context) (bool, error) {
+	return !db.noHistory, nil
+}
+
+func (db *testDB) Snapshot(_ context.Context) (migdb.Catalog, error) {
+	return migdb.Catalog{}, nil
+}
+
+// FindAllApplied возвращает примененные миграции по убыванию версии, как Pg.
+func (db *testDB) FindAllApplied(_ context.Context) ([]migdb.MigrateInfo, error) {
+	out := make([]migdb.MigrateInfo, 0, len(db.applied))
+	for _, name := range db.applied {
+		out = append(out, migdb.MigrateInfo{Name: name})
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		vi, _ := migfile.ParseVersion(out[i].Name)
+		vj, _ := migfile.ParseVersion(out[j].Name)
+		return vi.Compare(vj) > 0
+	})
+
+	return out, nil
+}
+
+func (db *testDB) FindAllRepeatable(_ context.Context) ([]migdb.RepeatableInfo, error) {
+	return db.repeatable, nil
+}
+
+// testLogger запоминает предупреждения.
+type testLogger struct {
+	warnings []string
+}
+
+func (l *testLogger) Info(_ ...any)  {}
+func (l *testLogger) Error(_ ...any) {}
+func (l *testLogger) Debug(_ ...any) {}
+
+func (l *testLogger) Warning(v ...any) {
+	parts := make([]string, 0, len(v))
+	for _, p := range v {
+		if s, ok := p.(string); ok {
+			parts = append(parts, s)
+		}
+	}
+	l.warnings = append(l.warnings, strings.Join(parts, " "))
+}
+
+// newTestMigrator мигратор с каталогом из sql миграций files и историей applied.
+// При applied == nil мигратор работает без базы.
+func newTestMigrator(t *testing.T, files []string, applied []string) (*Migrator, *testLogger) {
+	t.Helper()
+
+	dir := t.TempDir()
+	for _, name := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(testSQLContent), 0o600))
+	}
+
+	l := &testLogger{}
+	m, err := New(l, dir, nil)
+	require.NoError(t, err)
+	if applied != nil {
+		m.db = &testDB{applied: applied}
+	}
+
+	return m, l
+}
+
+func TestMigrator_OutOfOrderPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		files       []string
+		applied     []string
+		policy      OutOfOrderPolicy
+		wantPending []string
+		wantOut     []string
+		wantErr     bool
+		wantWarning bool
+	}{
+		{
+			name:        "in order",
+			files:       []string{"00001_a.sql", "00002_b.sql", "00003_c.sql"},
+			applied:     []string{"00001_a.sql"},
+			policy:      OutOfOrderError,
+			wantPending: []string{"00002_b.sql", "00003_c.sql"},
+		},
+		{
+			name:        "error",
+			files:       []string{"00001_a.sql", "00002_b.sql", "00003_c.sql"},
+			applied:     []string{"00001_a.sql", "00003_c.sql"},
+			policy:      OutOfOrderError,
+			wantPending: []string{"00002_b.sql"},
+			wantOut:     []string{"00002_b.sql"},
+			wantErr:     true,
+		},
+		{
+			name:        "warn",
+			files:       []string{"00001_a.sql", "00002_b.sql", "00003_c.sql"},
+			applied:     []string{"00001_a.sql", "00003_c.sql"},
+			policy:      OutOfOrderWarn,
+			wantPending: []string{"00002_b.sql"},
+			wantOut:     []string{"00002_b.sql"},
+			wantWarning: true,
+		},
+		{
+			name:        "allow",
+			files:       []string{"00001_a.sql", "00002_b.sql", "00003_c.sql"},
+			applied:     []string{"00001_a.sql", "00003_c.sql"},
+			policy:      OutOfOrderAllow,
+			wantPending: []string{"00002_b.sql"},
+			wantOut:     []string{"00002_b.sql"},
+		},
+		{
+			name:        "applied timestamp above sequential",
+			files:       []string{"00001_a.sql", "00002_b.sql", "20230101120000_ts.sql", "20230201120000_next.sql"},
+			applied:     []string{"00001_a.sql", "20230101120000_ts.sql"},
+			policy:      OutOfOrderError,
+			wantPending: []string{"00002_b.sql", "20230201120000_next.sql"},
+			wantOut:     []string{"00002_b.sql"},
+			wantErr:     true,
+		},
+		{
+			name:        "applied file missing in directory",
+			files:       []string{"00001_a.sql", "00002_b.sql", "00004_d.sql"},
+			applied:     []string{"00001_a.sql", "00003_gone.sql"},
+			policy:      OutOfOrderError,
+			wantPending: []string{"00002_b.sql", "00004_d.sql"},
+			wantOut:     []string{"00002_b.sql"},
+			wantErr:     true,
+		},
+		{
+			name:        "nothing applied",
+			files:       []string{"00002_b.sql", "00001_a.sql"},
+			applied:     []string{},
+			policy:      OutOfOrderError,
+			wantPending: []string{"00001_a.sql", "00002_b.sql"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, l := newTestMigrator(t, tt.files, tt.applied)
+			require.NoError(t, m.SetOutOfOrderPolicy(tt.policy))
+
+			out, err := m.OutOfOrder()
+			require.NoError(t, err)
+			require.Equal(t, tt.wantOut, migrationNames(out))
+
+			plan, err := m.Plan()
+			require.NoError(t, err)
+			names := make([]string, 0, len(plan))
+			for _, item := range plan {
+				names = append(names, item.Name)
+				require.Equal(t, contains(tt.wantOut, item.Name), item.OutOfOrder, item.Name)
+			}
+			require.Equal(t, tt.wantPending, names)
+
+			err = m.Validate()
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrOutOfOrder)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.wantWarning, len(l.warnings) > 0)
+		})
+	}
+}
+
+func TestMigrator_PlanOffline(t *testing.T) {
+	m, _ := newTestMigrator(t, []string{"00002_b.sql", "00001_a.sql", "R__views.sql"}, nil)
+	require.True(t, m.IsOffline())
+
+	plan, err := m.Plan()
+	require.NoError(t, err)
+	require.Len(t, plan, 2)
+	require.Equal(t, "00001_a.sql", plan[0].Name)
+	require.Equal(t, "00002_b.sql", plan[1].Name)
+
+	_, err = m.OutOfOrder()
+	require.ErrorIs(t, err, ErrOffline)
+}
+
+func TestValidateOutOfOrder(t *testing.T) {
+	for _, p := range []OutOfOrderPolicy{OutOfOrderError, OutOfOrderWarn, OutOfOrderAllow} {
+		require.NoError(t, ValidateOutOfOrder(p))
+	}
+	require.Error(t, ValidateOutOfOrder("ignore"))
+
+	m, _ := newTestMigrator(t, nil, nil)
+	require.Error(t, m.SetOutOfOrderPolicy("ignore"))
+	require.Equal(t, OutOfOrderError, m.outOfOrder)
+}
+
+func migrationNames(list []Migration) []string {
+	if len(list) == 0 {
+		return nil
+	}
+
+	out := make([]string, 0, len(list))
+	for _, mg := range list {
+		out = append(out, mg.Name)
+	}
+
+	return out
+}
+
+func contains(list []string, name string) bool {
+	for _, s := range list {
+		if s == name {
+			return true
+		}
+	}
+
+	return false
+}
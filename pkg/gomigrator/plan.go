@@ -0,0 +1,59 @@
+package gomigrator
+
+import (
+	"context"
+
+	migfile "github.com/dimonk33/sql-migrator/internal/file"
+)
+
+// PlanItem миграция в плане применения.
+type PlanItem struct {
+	Migration
+	OutOfOrder bool
+}
+
+// Plan возвращает миграции, которые будут применены командой up, в порядке применения.
+// Без подключения к базе в план входят все версионные миграции каталога.
+func (m *Migrator) Plan() ([]PlanItem, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+	defer cancel()
+
+	pending, outOfOrder, err := m.planned(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	skipped := make(map[string]struct{}, len(outOfOrder))
+	for _, mg := range outOfOrder {
+		skipped[mg.Name] = struct{}{}
+	}
+
+	out := make([]PlanItem, 0, len(pending))
+	for _, mg := range pending {
+		_, ok := skipped[mg.Name]
+		out = append(out, PlanItem{
+			Migration:  mg,
+			OutOfOrder: ok,
+		})
+	}
+
+	return out, nil
+}
+
+// Непримененные миграции, а без подключения к базе - все миграции каталога.
+func (m *Migrator) planned(ctx context.Context) ([]migfile.Migration, []migfile.Migration, error) {
+	mlist, _, err := m.scanDir(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if m.IsOffline() {
+		return mlist, nil, nil
+	}
+
+	if err = m.connect(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	return m.pending(ctx, mlist)
+}
@@ -0,0 +1,128 @@
+package gomigrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	migdb "github.com/dimonk33/sql-migrator/internal/db"
+)
+
+// CatalogChange отличие объекта схемы базы.
+type CatalogChange = migdb.CatalogChange
+
+// Виды отличия объекта схемы в CatalogChange.Change.
+const (
+	ChangeAdded   = migdb.ChangeAdded
+	ChangeRemoved = migdb.ChangeRemoved
+	ChangeChanged = migdb.ChangeChanged
+)
+
+// ReversibleResult результат проверки обратимости миграции. Changes - объекты схемы,
+// которые Down часть не вернула к состоянию до применения миграции.
+type ReversibleResult struct {
+	Name    string
+	Changes []CatalogChange
+	Err     error
+}
+
+var ErrNotReversible = errors.New("миграция не восстанавливает схему при откате")
+
+// CheckReversible применяет непримененные миграции по одной с проверкой обратимости:
+// снимок схемы, up, down, сравнение схемы со снимком, повторный up.
+// Проверка останавливается на первой миграции, которая не прошла проверку,
+// так как схема после нее может отличаться от ожидаемой следующими миграциями.
+// Повторяемые миграции не применяются.
+func (m *Migrator) CheckReversible() ([]ReversibleResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+	defer cancel()
+
+	if err := m.connect(ctx); err != nil {
+		return nil, err
+	}
+
+	mlist, _, err := m.scanDir(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pending, outOfOrder, err := m.pending(ctx, mlist)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = m.checkOutOfOrder(outOfOrder); err != nil {
+		return nil, err
+	}
+
+	if len(pending) == 0 {
+		return nil, ErrNoMigrations
+	}
+
+	out := make([]ReversibleResult, 0, len(pending))
+	for _, mg := range pending {
+		m.logger.Info("Проверка обратимости миграции", mg.Name)
+
+		dbSign := filepath.Base(mg.Path)
+		if !m.db.Lock(ctx, dbSign) {
+			return out, fmt.Errorf("миграция %s заблокирована", dbSign)
+		}
+
+		res := ReversibleResult{Name: mg.Name}
+		res.Changes, res.Err = m.roundTrip(ctx, mg)
+
+		if !m.db.Unlock(ctx, dbSign) {
+			m.logger.Error("ошибка разблокировки миграции ", dbSign)
+		}
+
+		out = append(out, res)
+
+		if res.Err != nil {
+			return out, res.Err
+		}
+
+		if len(res.Changes) > 0 {
+			return out, fmt.Errorf("%w: %s", ErrNotReversible, mg.Name)
+		}
+
+		m.logger.Info("Миграция", mg.Name, "обратима и применена")
+	}
+
+	return out, nil
+}
+
+func (m *Migrator) roundTrip(ctx context.Context, mg Migration) ([]CatalogChange, error) {
+	mExecuter, err := m.newExecuter(mg.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	before, err := m.db.Snapshot(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка снимка схемы: %w", err)
+	}
+
+	if err = mExecuter.UpExec(ctx, mg.Path); err != nil {
+		return nil, fmt.Errorf("ошибка применения миграции %s: %w", mg.Name, err)
+	}
+
+	if err = mExecuter.DownExec(ctx, mg.Path); err != nil {
+		return nil, fmt.Errorf("ошибка отмены миграции %s: %w", mg.Name, err)
+	}
+
+	restored, err := m.db.Snapshot(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка снимка схемы: %w", err)
+	}
+
+	if changes := migdb.DiffCatalog(before, restored); len(changes) > 0 {
+		return changes, nil
+	}
+
+	if err = mExecuter.UpExec(ctx, mg.Path); err != nil {
+		return nil, fmt.Errorf("ошибка повторного применения миграции %s: %w", mg.Name, err)
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,27 @@
+package gomigrator
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	migdb "github.com/dimonk33/sql-migrator/internal/db"
+)
+
+// DumpSchema записывает схему базы, построенную по системному каталогу, в виде sql скрипта.
+// Служебные таблицы мигратора в схему не входят.
+func (m *Migrator) DumpSchema(w io.Writer) error {
+	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+	defer cancel()
+
+	if err := m.connect(ctx); err != nil {
+		return err
+	}
+
+	c, err := m.db.Snapshot(ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка снимка схемы: %w", err)
+	}
+
+	return migdb.WriteSchema(w, c)
+}
@@ -0,0 +1,32 @@
+package gomigrator
+
+import (
+	"github.com/dimonk33/sql-migrator/internal/executer"
+	migfile "github.com/dimonk33/sql-migrator/internal/file"
+)
+
+const (
+	UpDirection   = executer.UpDirection
+	DownDirection = executer.DownDirection
+
+	SQLUpPartID   = migfile.SQLUpPartID
+	SQLDownPartID = migfile.SQLDownPartID
+)
+
+// SQLSection запросы sql миграции для одного направления.
+type SQLSection = executer.SQLSection
+
+var ErrWrongFileFormat = executer.ErrWrongFileFormat
+
+// ParseSQL разбирает содержимое sql миграции тем же разборщиком, что и up/down:
+// с учетом диалекта разметки, строк, $$ блоков и комментариев.
+// path используется для выбора диалекта и поиска парных файлов.
+func ParseSQL(path string, content string, dir int) (SQLSection, error) {
+	return executer.ParseSQL(path, content, dir)
+}
+
+// SplitStatements разделяет sql текст на запросы, блоки StatementBegin/StatementEnd
+// выполняются одним запросом.
+func SplitStatements(text string) []string {
+	return executer.SplitStatements(text)
+}
@@ -0,0 +1,133 @@
+package gomigrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	migdb "github.com/dimonk33/sql-migrator/internal/db"
+	"github.com/dimonk33/sql-migrator/internal/executer"
+)
+
+// StatementError ошибка выполнения запроса миграции при проверке.
+type StatementError = migdb.StatementError
+
+// VerifyResult результат проверки миграции. Skipped - причина, по которой миграция не проверялась.
+type VerifyResult struct {
+	Name    string
+	Skipped string
+	NoDown  bool
+	Err     error
+}
+
+var ErrVerifyFailed = errors.New("проверка миграций не пройдена")
+
+// Verify выполняет Up и Down части непримененных миграций в транзакции, которая всегда
+// откатывается, и возвращает результат по каждой миграции. gomigrate_info не изменяется.
+// Go миграции и миграции вне транзакции не проверяются.
+func (m *Migrator) Verify() ([]VerifyResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+	defer cancel()
+
+	if err := m.connect(ctx); err != nil {
+		return nil, err
+	}
+
+	mlist, rlist, err := m.scanDir(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pending, outOfOrder, err := m.pending(ctx, mlist)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = m.checkOutOfOrder(outOfOrder); err != nil {
+		return nil, err
+	}
+
+	changed, err := m.changedRepeatable(ctx, rlist)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pending) == 0 && len(changed) == 0 {
+		return nil, ErrNoMigrations
+	}
+
+	sqlExecuter := executer.NewSQLMigrate(m.db)
+	out := make([]VerifyResult, 0, len(pending)+len(changed))
+	items := make([]migdb.VerifyItem, 0, len(pending)+len(changed))
+	index := make([]int, 0, len(pending)+len(changed))
+
+	add := func(res VerifyResult, item *migdb.VerifyItem) {
+		out = append(out, res)
+		if item != nil {
+			items = append(items, *item)
+			index = append(index, len(out)-1)
+		}
+	}
+
+	for _, mg := range pending {
+		res := VerifyResult{Name: mg.Name}
+
+		if migrateType(mg.Path) != SQLMigration {
+			res.Skipped = migrateType(mg.Path) + " миграция выполняется вне транзакции проверки"
+			add(res, nil)
+			continue
+		}
+
+		up, err := sqlExecuter.Section(mg.Path, executer.UpDirection)
+		if err != nil {
+			res.Err = fmt.Errorf("ошибка парсинга файла: %w", err)
+			add(res, nil)
+			continue
+		}
+
+		if up.NoTx {
+			res.Skipped = "миграция выполняется вне транзакции"
+			add(res, nil)
+			continue
+		}
+
+		down, err := sqlExecuter.Section(mg.Path, executer.DownDirection)
+		if err != nil && !errors.Is(err, executer.ErrWrongFileFormat) {
+			res.Err = fmt.Errorf("ошибка парсинга файла: %w", err)
+			add(res, nil)
+			continue
+		}
+
+		add(res, &migdb.VerifyItem{Name: mg.Name, Up: up.Statements, Down: down.Statements})
+	}
+
+	for _, k := range sortedKeys(changed) {
+		res := VerifyResult{Name: k}
+
+		sqls, err := sqlExecuter.RepeatStatements(changed[k].Path)
+		if err != nil {
+			res.Err = err
+			add(res, nil)
+			continue
+		}
+
+		add(res, &migdb.VerifyItem{Name: k, Up: sqls})
+	}
+
+	checked, err := m.db.VerifyTx(ctx, items)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка проверки миграций: %w", err)
+	}
+
+	for i, res := range checked {
+		out[index[i]].Err = res.Err
+		out[index[i]].NoDown = res.NoDown && !isRepeatable(changed, res.Name)
+	}
+
+	return out, nil
+}
+
+func isRepeatable(rlist map[string]repeatableFile, name string) bool {
+	_, ok := rlist[name]
+	return ok
+}
@@ -0,0 +1,84 @@
+package gomigrator
+
+import (
+	"context"
+	"fmt"
+
+	migfile "github.com/dimonk33/sql-migrator/internal/file"
+)
+
+const (
+	VersionTimestamp  = migfile.VersionTimestamp
+	VersionSequential = migfile.VersionSequential
+
+	LayoutSingle = migfile.LayoutSingle
+	LayoutSplit  = migfile.LayoutSplit
+)
+
+type Renamed = migfile.Renamed
+
+func (m *Migrator) SetVersioning(v string) error {
+	if err := migfile.ValidateVersioning(v); err != nil {
+		return err
+	}
+
+	m.versioning = v
+
+	return nil
+}
+
+func (m *Migrator) SetLayout(l string) error {
+	if err := migfile.ValidateLayout(l); err != nil {
+		return err
+	}
+
+	m.layout = l
+
+	return nil
+}
+
+// Fix переводит непримененные миграции с отметкой времени
+// на последовательную нумерацию после последней примененной или существующей версии.
+func (m *Migrator) Fix() ([]Renamed, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+	defer cancel()
+
+	if err := m.connect(ctx); err != nil {
+		return nil, err
+	}
+
+	mlist, _, err := m.scanDir(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	appliedMigrations, err := m.db.FindAllApplied(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения миграций из базы: %w", err)
+	}
+
+	applied := make(map[string]struct{}, len(appliedMigrations))
+	appliedVersions := make([]migfile.Version, 0, len(appliedMigrations))
+	versions := make([]migfile.Version, 0, len(mlist)+len(appliedMigrations))
+	for _, am := range appliedMigrations {
+		v, err := migfile.ParseVersion(am.Name)
+		if err != nil {
+			return nil, err
+		}
+		applied[am.Name] = struct{}{}
+		appliedVersions = append(appliedVersions, v)
+		versions = append(versions, v)
+	}
+
+	for _, mg := range mlist {
+		versions = append(versions, mg.Version)
+	}
+
+	plan := migfile.FixPlan(mlist, applied, migfile.FixStart(versions, appliedVersions))
+
+	if err = migfile.ApplyFix(m.dirPath, plan); err != nil {
+		return nil, fmt.Errorf("ошибка перенумерации миграций: %w", err)
+	}
+
+	return plan, nil
+}
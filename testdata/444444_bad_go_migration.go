@@ -20,5 +20,5 @@ func up(tx *sql.Tx) error {
 		return err
 	}
 
-	return tx.Commit()
+	return nil
 }
@@ -20,7 +20,7 @@ func up(tx *sql.Tx) error {
 		return err
 	}
 
-	return tx.Commit()
+	return nil
 }
 
 func down(tx *sql.Tx) error {
@@ -29,5 +29,5 @@ func down(tx *sql.Tx) error {
 		return err
 	}
 
-	return tx.Commit()
+	return nil
 }